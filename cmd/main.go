@@ -1,10 +1,10 @@
 package main
 
-import "mirror-wget/internal/engine"
+import (
+	"mirror-wget/internal/command"
+	"os"
+)
 
 func main() {
-	err := engine.Handle()
-	if err != nil {
-		panic(err)
-	}
+	os.Exit(command.Run(os.Args[1:]))
 }
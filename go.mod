@@ -3,9 +3,15 @@ module mirror-wget
 go 1.24.2
 
 require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/andybalholm/brotli v1.1.1
 	github.com/riking/cssparse v0.0.0-20180325025645-c37ded0aac89
 	github.com/temoto/robotstxt v1.1.2
 	golang.org/x/net v0.44.0
+	golang.org/x/term v0.35.0
 )
 
-require golang.org/x/text v0.29.0 // indirect
+require (
+	golang.org/x/sys v0.36.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
+)
@@ -1,33 +1,648 @@
 package cli
 
 import (
+	"bufio"
+	"encoding/json"
 	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"mirror-wget/internal/logging"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // DefaultLevel значение уровня рекурсии по умолчанию < 0 - нет ограничения
 const DefaultLevel = -1
 
+// DefaultRecursiveLevel глубина рекурсии для -r без явного -l, как в wget
+const DefaultRecursiveLevel = 5
+
+// DefaultWorkers число одновременных загрузок по умолчанию. Загрузки ждут сеть, а не CPU,
+// поэтому значение не зависит от числа процессоров
+const DefaultWorkers = 8
+
+// DefaultMaxPerHost число одновременных загрузок с одного хоста по умолчанию
+const DefaultMaxPerHost = 4
+
+// DefaultTries число попыток загрузки по умолчанию
+const DefaultTries = 3
+
+// DefaultWaitRetry максимальная пауза между повторами по умолчанию
+const DefaultWaitRetry = Duration(10 * time.Second)
+
+// DefaultMaxRedirect сколько редиректов выполняется по умолчанию, как в wget
+const DefaultMaxRedirect = 20
+
+// Значения --offsite-redirects
+const (
+	RedirectsSkip   = "skip"   // не скачивать документ, если редирект уводит за область обхода
+	RedirectsFollow = "follow" // скачивать документ, но ссылки с него проверять как обычно
+)
+
+// Значения --compression
+const (
+	CompressionAuto = "auto" // запрашивать gzip, deflate и br и раскодировать ответы
+	CompressionNone = "none" // запрашивать несжатые ответы
+)
+
+// DefaultCacheSize предел размера кеша ответов по умолчанию
+const DefaultCacheSize = ByteSize(1 << 30)
+
+// DefaultHostBurst сколько запросов к хосту можно отправить подряд при --host-rate по умолчанию
+const DefaultHostBurst = 1
+
+// DefaultMaxRatio во сколько раз раскодированный ответ может быть больше сжатого по умолчанию
+const DefaultMaxRatio = 100
+
+// DefaultRetryOn ошибки, после которых загрузка повторяется по умолчанию
+var DefaultRetryOn = []string{"429", "5xx", "network"}
+
 // Config конфигурация утилиты
 type Config struct {
-	URL   string
-	Level int
+	URLs      []string `json:"urls"`       // стартовые URL
+	InputFile string   `json:"input_file"` // -i: файл со стартовыми URL, "-" - стандартный ввод
+	Level     int      `json:"level"`
+
+	Recursive       bool     `json:"recursive"`            // -r: рекурсивный обход
+	Mirror          bool     `json:"mirror"`               // -m: -r с неограниченной глубиной
+	NoParent        bool     `json:"no_parent"`            // -np: не подниматься выше каталога стартового URL
+	NoHostDirs      bool     `json:"no_host_directories"`  // -nH: не создавать каталог с именем хоста
+	CutDirs         int      `json:"cut_dirs"`             // --cut-dirs: сколько первых каталогов пути отбросить
+	OutputPrefix    string   `json:"directory_prefix"`     // -P: каталог, в который сохраняется зеркало
+	ConvertLinks    bool     `json:"convert_links"`        // -k: переписывать ссылки на локальные
+	PageRequisites  bool     `json:"page_requisites"`      // -p: скачивать ресурсы, необходимые для отображения страницы
+	Accept          []string `json:"accept"`               // -A: суффиксы или шаблоны имён файлов, которые нужно сохранять
+	Reject          []string `json:"reject"`               // -R: суффиксы или шаблоны имён файлов, которые не нужно сохранять
+	Domains         []string `json:"domains"`              // -D: домены, на которые разрешено переходить
+	Wait            Duration `json:"wait"`                 // -w: пауза между запросами к одному хосту
+	RandomWait      bool     `json:"random_wait"`          // --random-wait: случайная пауза от 0.5 до 1.5 * Wait
+	HostRate        float64  `json:"host_rate"`            // --host-rate: запросов в секунду к одному хосту, 0 - без ограничения
+	HostBurst       int      `json:"host_burst"`           // --host-burst: сколько запросов к хосту можно отправить подряд при --host-rate
+	LimitRate       ByteSize `json:"limit_rate"`           // --limit-rate: общая скорость загрузки в байтах в секунду, 0 - без ограничения
+	UserAgent       string   `json:"user_agent"`           // -U: пользовательский агент
+	Robots          bool     `json:"robots"`               // -e robots=off отключает учёт robots.txt
+	Headers         []string `json:"headers"`              // --header: дополнительные заголовки запросов вида "Name: value"
+	Referer         string   `json:"referer"`              // --referer: заголовок Referer для стартовых URL
+	Workers         int      `json:"workers"`              // --workers: число одновременных загрузок
+	StorageWorkers  int      `json:"storage_workers"`      // --storage-workers: воркеры переписывания ссылок (-k), 0 - по числу CPU
+	MaxPerHost      int      `json:"max_per_host"`         // --max-per-host: одновременных загрузок с одного хоста, 0 - без ограничения
+	Spider          bool     `json:"spider"`               // --spider: обойти сайт без сохранения файлов и вывести найденные URL
+	NoProgress      bool     `json:"no_progress"`          // --no-progress: не показывать прогресс в терминале
+	Tries           int      `json:"tries"`                // -t: число попыток загрузки, 0 - без ограничения
+	RetryOn         []string `json:"retry_on"`             // --retry-on: коды, классы кодов (5xx) и network, после которых загрузка повторяется
+	WaitRetry       Duration `json:"wait_retry"`           // --waitretry: максимальная пауза между повторами
+	Timeout         Duration `json:"timeout"`              // -T: значение для всех незаданных таймаутов
+	DNSTimeout      Duration `json:"dns_timeout"`          // --dns-timeout: разрешение имени
+	ConnectTimeout  Duration `json:"connect_timeout"`      // --connect-timeout: установка соединения
+	TLSTimeout      Duration `json:"tls_timeout"`          // --tls-timeout: TLS рукопожатие
+	HeaderTimeout   Duration `json:"response_timeout"`     // --response-timeout: ожидание заголовков ответа
+	ReadTimeout     Duration `json:"read_timeout"`         // --read-timeout: простой при чтении тела ответа
+	MaxFileSize     ByteSize `json:"max_filesize"`         // --max-filesize: документы больше не скачиваются, 0 - без ограничения
+	Continue        bool     `json:"continue"`             // -c, --continue: дописывать частично скачанные файлы
+	Timestamping    bool     `json:"timestamping"`         // -N: не скачивать документы, не изменившиеся с прошлого обхода
+	LoadCookies     string   `json:"load_cookies"`         // --load-cookies: cookies.txt, загружаемый перед обходом
+	SaveCookies     string   `json:"save_cookies"`         // --save-cookies: cookies.txt, сохраняемый после обхода
+	KeepSession     bool     `json:"keep_session_cookies"` // --keep-session-cookies: сохранять и сессионные cookies
+	User            string   `json:"user"`                 // --user: логин для хостов стартовых URL
+	NoNetrc         bool     `json:"no_netrc"`             // --no-netrc: не читать учётные данные из ~/.netrc
+	AuthNoChallenge bool     `json:"auth_no_challenge"`    // --auth-no-challenge: отправлять Basic, не дожидаясь 401
+	Proxy           string   `json:"proxy"`                // --proxy: прокси для всех запросов вместо HTTP_PROXY и HTTPS_PROXY
+	NoProxyHosts    string   `json:"noproxy"`              // --noproxy: хосты, к которым подключаться напрямую, вместо NO_PROXY
+	NoProxy         bool     `json:"no_proxy"`             // --no-proxy: не использовать прокси
+	ProxyUser       string   `json:"proxy_user"`           // --proxy-user: логин прокси
+	CACertificate   string   `json:"ca_certificate"`       // --ca-certificate: PEM файл центров сертификации
+	CADirectory     string   `json:"ca_directory"`         // --ca-directory: каталог PEM файлов центров сертификации
+	Certificate     string   `json:"certificate"`          // --certificate: клиентский сертификат (mTLS)
+	PrivateKey      string   `json:"private_key"`          // --private-key: ключ клиентского сертификата
+	NoCheckCert     bool     `json:"no_check_certificate"` // --no-check-certificate: не проверять сертификат сервера
+	TLSMinVersion   string   `json:"tls_min_version"`      // --tls-min-version: минимальная версия TLS (1.2, 1.3)
+	MaxRedirect     int      `json:"max_redirect"`         // --max-redirect: сколько редиректов выполнять, 0 - не выполнять
+	OffsiteRedirect string   `json:"offsite_redirects"`    // --offsite-redirects: skip или follow для редиректов за область обхода
+	Compression     string   `json:"compression"`          // --compression: auto или none
+	KeepEncoded     bool     `json:"keep_encoded"`         // --keep-encoded: сохранять сжатые ответы как есть, без раскодирования
+	MaxRatio        int      `json:"compression_ratio"`    // --max-compression-ratio: предел отношения раскодированного размера к сжатому, 0 - без ограничения
+	NoCache         bool     `json:"no_cache"`             // --no-cache: не использовать кеш ответов на диске
+	CacheOnly       bool     `json:"cache_only"`           // --cache-only: отвечать только из кеша, без сети
+	CacheDir        string   `json:"cache_dir"`            // --cache-dir: каталог кеша, по умолчанию в пользовательском каталоге кешей
+	CacheSize       ByteSize `json:"cache_size"`           // --cache-size: предел размера кеша, 0 - без ограничения
+	Login           *Login   `json:"login,omitempty"`      // вход через форму перед обходом, только в файле задания
+	Credentials     []string `json:"credentials"`          // учётные данные (Secrets), с которыми запущено задание; resume требует их снова
+
+	logging.Options
+	Secrets
+
+	ConfigFile  string `json:"-"` // --config: файл задания (JSON или TOML)
+	Profile     string `json:"-"` // --profile: профиль из файла задания
+	PrintConfig bool   `json:"-"` // --print-config: вывести итоговую конфигурацию и выйти
 }
 
-// NewConfig собирает конфигурацию утилиты из флагов и аргументов командной строки
-func NewConfig() (*Config, error) {
-	var config Config
+// defaultConfig возвращает конфигурацию по умолчанию
+func defaultConfig() Config {
+	return Config{
+		Level:      DefaultLevel,
+		Robots:     true,
+		Workers:    DefaultWorkers,
+		MaxPerHost: DefaultMaxPerHost,
+		Tries:      DefaultTries,
+		RetryOn:    append([]string(nil), DefaultRetryOn...),
+		WaitRetry:  DefaultWaitRetry,
 
-	level := flag.Int("l", DefaultLevel, "level of recursion")
-	flag.Parse()
+		MaxRedirect:     DefaultMaxRedirect,
+		OffsiteRedirect: RedirectsSkip,
+		Compression:     CompressionAuto,
+		MaxRatio:        DefaultMaxRatio,
+		HostBurst:       DefaultHostBurst,
+		CacheSize:       DefaultCacheSize,
+	}
+}
+
+// NewConfig собирает конфигурацию утилиты из файла задания, флагов и аргументов командной строки.
+// Флаги командной строки перекрывают значения из файла
+func NewConfig(args []string) (*Config, error) {
+	// первый проход нужен только чтобы узнать файл задания и профиль
+	probe := defaultConfig()
+	if err := newFlagSet(&probe).Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := defaultConfig()
+	levelSet := false
+	if probe.ConfigFile != "" {
+		layers, err := loadJobFile(probe.ConfigFile, probe.Profile)
+		if err != nil {
+			return nil, err
+		}
+		for _, layer := range layers {
+			if err := config.apply(layer); err != nil {
+				return nil, fmt.Errorf("%s: %v", probe.ConfigFile, err)
+			}
+			if _, ok := layer["level"]; ok {
+				levelSet = true
+			}
+		}
+	} else if probe.Profile != "" {
+		return nil, errors.New("--profile requires --config")
+	}
+
+	// второй проход: флаги перекрывают значения из файла
+	fs := newFlagSet(&config)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "l" || f.Name == "level" {
+			levelSet = true
+		}
+	})
 
-	args := flag.Args()
-	if len(args) == 0 {
+	if args := fs.Args(); len(args) > 0 {
+		config.URLs = args
+	}
+	if len(config.URLs) == 0 && config.InputFile == "" && !config.PrintConfig {
 		return nil, errors.New("no URL provided")
 	}
 
-	config.Level = *level
-	config.URL = args[0]
+	config.resolveLevel(levelSet)
+
+	if config.CutDirs < 0 {
+		return nil, errors.New("--cut-dirs must not be negative")
+	}
+	if config.Workers < 1 {
+		return nil, errors.New("--workers must be at least 1")
+	}
+	if config.StorageWorkers < 0 || config.MaxPerHost < 0 {
+		return nil, errors.New("--storage-workers and --max-per-host must not be negative")
+	}
+	for _, timeout := range []Duration{config.Timeout, config.DNSTimeout, config.ConnectTimeout, config.TLSTimeout, config.HeaderTimeout, config.ReadTimeout} {
+		if timeout < 0 {
+			return nil, errors.New("timeouts must not be negative")
+		}
+	}
+	if config.Tries < 0 {
+		return nil, errors.New("--tries must not be negative")
+	}
+	if config.MaxRedirect < 0 {
+		return nil, errors.New("--max-redirect must not be negative")
+	}
+	if config.OffsiteRedirect != RedirectsSkip && config.OffsiteRedirect != RedirectsFollow {
+		return nil, fmt.Errorf("invalid --offsite-redirects %q: expected skip or follow", config.OffsiteRedirect)
+	}
+	if config.Compression != CompressionAuto && config.Compression != CompressionNone {
+		return nil, fmt.Errorf("invalid --compression %q: expected auto or none", config.Compression)
+	}
+	if config.MaxRatio < 0 {
+		return nil, errors.New("--max-compression-ratio must not be negative")
+	}
+	if config.HostRate < 0 || config.HostBurst < 0 || config.LimitRate < 0 {
+		return nil, errors.New("--host-rate, --host-burst and --limit-rate must not be negative")
+	}
+	if config.NoCache && config.CacheOnly {
+		return nil, errors.New("--no-cache and --cache-only are mutually exclusive")
+	}
+	if config.CacheSize < 0 {
+		return nil, errors.New("--cache-size must not be negative")
+	}
+	if config.Login != nil {
+		if err := config.Login.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.Secrets.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Options.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
+
+// PrintUsage выводит описание флагов подкоманды mirror
+func PrintUsage(w io.Writer) {
+	config := defaultConfig()
+	fs := newFlagSet(&config)
+	fs.SetOutput(w)
+	fs.Usage()
+}
+
+// newFlagSet описывает флаги командной строки, записывающие значения в config.
+// Ошибки разбора возвращаются из Parse и ничего не выводят: о них сообщает вызывающий
+func newFlagSet(config *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	accept := newListValue(&config.Accept)
+	reject := newListValue(&config.Reject)
+	domains := newListValue(&config.Domains)
+	retryOn := newListValue(&config.RetryOn)
+	headers := newRepeatedValue(&config.Headers)
+
+	fs.StringVar(&config.InputFile, "i", config.InputFile, "download URLs found in `FILE` (- for standard input)")
+	fs.StringVar(&config.InputFile, "input-file", config.InputFile, "download URLs found in `FILE` (- for standard input)")
+	fs.IntVar(&config.Level, "l", config.Level, "level of recursion")
+	fs.IntVar(&config.Level, "level", config.Level, "level of recursion")
+	fs.BoolVar(&config.Recursive, "r", config.Recursive, "turn on recursive retrieving")
+	fs.BoolVar(&config.Recursive, "recursive", config.Recursive, "turn on recursive retrieving")
+	fs.BoolVar(&config.Mirror, "m", config.Mirror, "mirror: shortcut for -N -r -l -1")
+	fs.BoolVar(&config.Mirror, "mirror", config.Mirror, "mirror: shortcut for -N -r -l -1")
+	fs.BoolVar(&config.NoParent, "np", config.NoParent, "do not ascend to the parent directory")
+	fs.BoolVar(&config.NoParent, "no-parent", config.NoParent, "do not ascend to the parent directory")
+	fs.BoolVar(&config.NoHostDirs, "nH", config.NoHostDirs, "don't create host directories")
+	fs.BoolVar(&config.NoHostDirs, "no-host-directories", config.NoHostDirs, "don't create host directories")
+	fs.IntVar(&config.CutDirs, "cut-dirs", config.CutDirs, "ignore `N` remote directory components")
+	fs.StringVar(&config.OutputPrefix, "P", config.OutputPrefix, "save files to `PREFIX`/...")
+	fs.StringVar(&config.OutputPrefix, "directory-prefix", config.OutputPrefix, "save files to `PREFIX`/...")
+	fs.BoolVar(&config.ConvertLinks, "k", config.ConvertLinks, "make links in downloaded HTML or CSS point to local files")
+	fs.BoolVar(&config.ConvertLinks, "convert-links", config.ConvertLinks, "make links in downloaded HTML or CSS point to local files")
+	fs.BoolVar(&config.PageRequisites, "p", config.PageRequisites, "get all images, etc. needed to display HTML page")
+	fs.BoolVar(&config.PageRequisites, "page-requisites", config.PageRequisites, "get all images, etc. needed to display HTML page")
+	fs.Var(accept, "A", "comma-separated `LIST` of accepted extensions or patterns")
+	fs.Var(accept, "accept", "comma-separated `LIST` of accepted extensions or patterns")
+	fs.Var(reject, "R", "comma-separated `LIST` of rejected extensions or patterns")
+	fs.Var(reject, "reject", "comma-separated `LIST` of rejected extensions or patterns")
+	fs.Var(domains, "D", "comma-separated `LIST` of accepted domains")
+	fs.Var(domains, "domains", "comma-separated `LIST` of accepted domains")
+	fs.Var(&config.Wait, "w", "wait `SECONDS` between retrievals from one host")
+	fs.Var(&config.Wait, "wait", "wait `SECONDS` between retrievals from one host")
+	fs.BoolVar(&config.RandomWait, "random-wait", config.RandomWait, "wait from 0.5*WAIT...1.5*WAIT secs between retrievals")
+	fs.Float64Var(&config.HostRate, "host-rate", config.HostRate, "send at most `N` requests per second to one host (0 for no limit)")
+	fs.IntVar(&config.HostBurst, "host-burst", config.HostBurst, "allow `N` requests in a row to one host under --host-rate")
+	fs.Var(&config.LimitRate, "limit-rate", "limit download rate to `RATE` bytes per second (e.g. 200K, 2M)")
+	fs.StringVar(&config.UserAgent, "U", config.UserAgent, "identify as `AGENT` instead of the default user agent")
+	fs.StringVar(&config.UserAgent, "user-agent", config.UserAgent, "identify as `AGENT` instead of the default user agent")
+	fs.Var(headers, "header", "insert `HEADER` (\"Name: value\") among the headers sent in HTTP requests")
+	fs.StringVar(&config.Referer, "referer", config.Referer, "include 'Referer: `URL`' header in requests for the start URLs")
+	fs.IntVar(&config.Workers, "workers", config.Workers, "download up to `N` files at once")
+	fs.IntVar(&config.StorageWorkers, "storage-workers", config.StorageWorkers, "rewrite links in `N` files at once (0 for number of CPUs)")
+	fs.IntVar(&config.MaxPerHost, "max-per-host", config.MaxPerHost, "download up to `N` files at once from one host (0 for no limit)")
+	fs.BoolVar(&config.Spider, "spider", config.Spider, "crawl without saving files and list discovered URLs")
+	config.Options.RegisterFlags(fs)
+	fs.BoolVar(&config.NoProgress, "no-progress", config.NoProgress, "don't show live progress on a terminal")
+	fs.IntVar(&config.Tries, "t", config.Tries, "set number of tries to `N` (0 for unlimited)")
+	fs.IntVar(&config.Tries, "tries", config.Tries, "set number of tries to `N` (0 for unlimited)")
+	fs.Var(retryOn, "retry-on", "comma-separated `LIST` of status codes, classes like 5xx and network to retry on")
+	fs.Var(&config.WaitRetry, "waitretry", "wait at most `SECONDS` between retries of a retrieval")
+	fs.Var(&config.Timeout, "T", "set all unset timeouts to `SECONDS`")
+	fs.Var(&config.Timeout, "timeout", "set all unset timeouts to `SECONDS`")
+	fs.Var(&config.DNSTimeout, "dns-timeout", "set the DNS lookup timeout to `SECONDS`")
+	fs.Var(&config.ConnectTimeout, "connect-timeout", "set the connect timeout to `SECONDS`")
+	fs.Var(&config.TLSTimeout, "tls-timeout", "set the TLS handshake timeout to `SECONDS`")
+	fs.Var(&config.HeaderTimeout, "response-timeout", "wait at most `SECONDS` for response headers")
+	fs.Var(&config.ReadTimeout, "read-timeout", "abort a download that receives no data for `SECONDS`")
+	fs.Var(&config.MaxFileSize, "max-filesize", "skip files larger than `SIZE` (e.g. 500M, 2G)")
+	fs.BoolVar(&config.Continue, "c", config.Continue, "resume getting a partially-downloaded file")
+	fs.BoolVar(&config.Continue, "continue", config.Continue, "resume getting a partially-downloaded file")
+	fs.BoolVar(&config.Timestamping, "N", config.Timestamping, "don't re-retrieve files unless newer than local")
+	fs.BoolVar(&config.Timestamping, "timestamping", config.Timestamping, "don't re-retrieve files unless newer than local")
+	fs.StringVar(&config.LoadCookies, "load-cookies", config.LoadCookies, "load cookies from `FILE` before session")
+	fs.StringVar(&config.SaveCookies, "save-cookies", config.SaveCookies, "save cookies to `FILE` after session")
+	fs.BoolVar(&config.KeepSession, "keep-session-cookies", config.KeepSession, "also save session (non-permanent) cookies")
+	fs.StringVar(&config.User, "user", config.User, "set both http and robots.txt user to `USER`")
+	fs.StringVar(&config.User, "http-user", config.User, "set both http and robots.txt user to `USER`")
+	config.Secrets.RegisterFlags(fs)
+	fs.BoolVar(&config.NoNetrc, "no-netrc", config.NoNetrc, "don't read credentials from ~/.netrc")
+	fs.BoolVar(&config.AuthNoChallenge, "auth-no-challenge", config.AuthNoChallenge, "send Basic credentials without waiting for a challenge")
+	fs.StringVar(&config.Proxy, "proxy", config.Proxy, "use proxy `URL` (http://, https:// or socks5://) instead of HTTP_PROXY/HTTPS_PROXY")
+	fs.StringVar(&config.NoProxyHosts, "noproxy", config.NoProxyHosts, "comma-separated `LIST` of hosts to connect to directly instead of NO_PROXY")
+	fs.BoolVar(&config.NoProxy, "no-proxy", config.NoProxy, "explicitly turn off proxy")
+	fs.StringVar(&config.ProxyUser, "proxy-user", config.ProxyUser, "set `USER` as proxy username")
+	fs.StringVar(&config.CACertificate, "ca-certificate", config.CACertificate, "`FILE` with the bundle of CAs")
+	fs.StringVar(&config.CADirectory, "ca-directory", config.CADirectory, "`DIR` where hash list of CAs is stored")
+	fs.StringVar(&config.Certificate, "certificate", config.Certificate, "client certificate `FILE`")
+	fs.StringVar(&config.PrivateKey, "private-key", config.PrivateKey, "private key `FILE`")
+	fs.BoolVar(&config.NoCheckCert, "no-check-certificate", config.NoCheckCert, "don't validate the server's certificate")
+	fs.IntVar(&config.MaxRedirect, "max-redirect", config.MaxRedirect, "follow at most `NUM` redirects per document, 0 disables redirects")
+	fs.StringVar(&config.OffsiteRedirect, "offsite-redirects", config.OffsiteRedirect, "`POLICY` for redirects leaving the crawl scope: skip or follow")
+	fs.StringVar(&config.Compression, "compression", config.Compression, "`TYPE` of compression to request: auto (gzip, deflate, br) or none")
+	fs.BoolVar(&config.KeepEncoded, "keep-encoded", config.KeepEncoded, "save compressed responses as received instead of decoded")
+	fs.IntVar(&config.MaxRatio, "max-compression-ratio", config.MaxRatio, "abort responses that decode to more than `NUM` times their compressed size, 0 disables the check")
+	fs.BoolVar(&config.NoCache, "no-cache", config.NoCache, "don't use the on-disk response cache")
+	fs.BoolVar(&config.CacheOnly, "cache-only", config.CacheOnly, "serve every request from the response cache without network access")
+	fs.StringVar(&config.CacheDir, "cache-dir", config.CacheDir, "keep the response cache in `DIR` (shared by jobs)")
+	fs.Var(&config.CacheSize, "cache-size", "limit the response cache to `SIZE` (e.g. 500M, 2G; 0 for no limit)")
+	fs.StringVar(&config.TLSMinVersion, "tls-min-version", config.TLSMinVersion, "minimum TLS `VERSION` (1.0, 1.1, 1.2 or 1.3)")
+	fs.Func("e", "execute a .wgetrc-style `COMMAND` (e.g. robots=off)", config.execute)
+	fs.Func("execute", "execute a .wgetrc-style `COMMAND` (e.g. robots=off)", config.execute)
+
+	fs.StringVar(&config.ConfigFile, "config", config.ConfigFile, "load job settings from JSON or TOML `FILE`")
+	fs.StringVar(&config.Profile, "profile", config.Profile, "use profile `NAME` from the job file")
+	fs.BoolVar(&config.PrintConfig, "print-config", config.PrintConfig, "print the effective configuration and exit")
+
+	return fs
+}
+
+// Seeds возвращает стартовые URL: из аргументов и файла задания, затем из -i
+func (c *Config) Seeds() ([]string, error) {
+	seeds := append([]string(nil), c.URLs...)
+	if c.InputFile == "" {
+		return seeds, nil
+	}
+
+	var r io.Reader = os.Stdin
+	if c.InputFile != "-" {
+		f, err := os.Open(c.InputFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	fromFile, err := readSeeds(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", c.InputFile, err)
+	}
+	return append(seeds, fromFile...), nil
+}
+
+// readSeeds читает URL по одному на строку; пустые строки и комментарии # пропускаются
+func readSeeds(r io.Reader) ([]string, error) {
+	var seeds []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		seeds = append(seeds, line)
+	}
+	return seeds, scanner.Err()
+}
+
+// resolveLevel выставляет глубину рекурсии по правилам wget:
+// -m означает бесконечную глубину и -N, -r без -l - DefaultRecursiveLevel,
+// без рекурсии скачивается только сама страница. Явный -l включает рекурсию.
+func (c *Config) resolveLevel(levelSet bool) {
+	if c.Mirror || levelSet {
+		c.Recursive = true
+	}
+	if c.Mirror {
+		c.Timestamping = true
+	}
+
+	switch {
+	case levelSet:
+	case c.Mirror:
+		c.Level = DefaultLevel
+	case c.Recursive:
+		c.Level = DefaultRecursiveLevel
+	default:
+		c.Level = 0
+	}
+}
+
+// Print выводит конфигурацию в формате JSON
+func (c *Config) Print(w io.Writer) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// execute выполняет команду в стиле .wgetrc вида name=value
+func (c *Config) execute(command string) error {
+	name, value, ok := strings.Cut(command, "=")
+	if !ok {
+		return fmt.Errorf("invalid command %q: expected name=value", command)
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer("_", "", "-", "").Replace(name)
+	value = strings.TrimSpace(value)
+
+	switch name {
+	case "robots":
+		enabled, err := parseSwitch(value)
+		if err != nil {
+			return fmt.Errorf("invalid command %q: %v", command, err)
+		}
+		c.Robots = enabled
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	return nil
+}
+
+// parseSwitch разбирает значения on/off в стиле .wgetrc
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "yes", "1", "true":
+		return true, nil
+	case "off", "no", "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off, got %q", value)
+}
+
+// Duration длительность, задаваемая в секундах ("1.5") или в формате time.ParseDuration ("300ms")
+type Duration time.Duration
+
+// Set реализует flag.Value
+func (d *Duration) Set(s string) error {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// String реализует flag.Value
+func (d *Duration) String() string {
+	if d == nil {
+		return "0s"
+	}
+	return time.Duration(*d).String()
+}
+
+// MarshalText реализует encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalJSON принимает как строку ("2s", "1.5"), так и число секунд
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return d.Set(s)
+}
+
+// ByteSize размер в байтах, задаваемый числом или с суффиксом K, M, G (степени 1024)
+type ByteSize int64
+
+// Set реализует flag.Value
+func (b *ByteSize) Set(s string) error {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+
+	multiplier := int64(1)
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			value = value[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", s)
+	}
+	*b = ByteSize(n * float64(multiplier))
+	return nil
+}
+
+// String реализует flag.Value
+func (b *ByteSize) String() string {
+	if b == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+// UnmarshalJSON принимает как число байт, так и строку вида "500M"
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = ByteSize(n)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid size %s", data)
+	}
+	return b.Set(s)
+}
+
+// listValue flag.Value для списков, разделённых запятыми; флаг можно повторять.
+// Первое значение из командной строки заменяет список из файла задания
+type listValue struct {
+	list *[]string
+	set  bool
+}
+
+// newListValue инициализирует listValue
+func newListValue(list *[]string) *listValue {
+	return &listValue{list: list}
+}
+
+// Set реализует flag.Value
+func (v *listValue) Set(s string) error {
+	if !v.set {
+		*v.list = nil
+		v.set = true
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			*v.list = append(*v.list, part)
+		}
+	}
+	return nil
+}
+
+// String реализует flag.Value
+func (v *listValue) String() string {
+	if v == nil || v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
+
+// repeatedValue flag.Value для повторяемого флага, значения которого могут содержать запятые.
+// Первое значение из командной строки заменяет список из файла задания
+type repeatedValue struct {
+	list *[]string
+	set  bool
+}
+
+// newRepeatedValue инициализирует repeatedValue
+func newRepeatedValue(list *[]string) *repeatedValue {
+	return &repeatedValue{list: list}
+}
+
+// Set реализует flag.Value
+func (v *repeatedValue) Set(s string) error {
+	if !v.set {
+		*v.list = nil
+		v.set = true
+	}
+	*v.list = append(*v.list, s)
+	return nil
+}
+
+// String реализует flag.Value
+func (v *repeatedValue) String() string {
+	if v == nil || v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, "; ")
+}
@@ -0,0 +1,386 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func _writeJobFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestParseConfigLevel тест глубины рекурсии для -r, -m, -l и без рекурсии
+func TestParseConfigLevel(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		level        int
+		recursive    bool
+		timestamping bool
+	}{
+		{
+			name:      "single page",
+			args:      []string{"https://example.com"},
+			level:     0,
+			recursive: false,
+		},
+		{
+			name:      "recursive",
+			args:      []string{"-r", "https://example.com"},
+			level:     DefaultRecursiveLevel,
+			recursive: true,
+		},
+		{
+			name:         "mirror",
+			args:         []string{"-m", "https://example.com"},
+			level:        DefaultLevel,
+			recursive:    true,
+			timestamping: true,
+		},
+		{
+			name:      "explicit level",
+			args:      []string{"-l", "2", "https://example.com"},
+			level:     2,
+			recursive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Level != tt.level || config.Recursive != tt.recursive || config.Timestamping != tt.timestamping {
+				t.Errorf("expected level %d recursive %v timestamping %v, got level %d recursive %v timestamping %v",
+					tt.level, tt.recursive, tt.timestamping, config.Level, config.Recursive, config.Timestamping)
+			}
+		})
+	}
+}
+
+// TestParseConfigFlags тест разбора списков, пауз и команд -e
+func TestParseConfigFlags(t *testing.T) {
+	config, err := NewConfig([]string{
+		"-A", "jpg,png", "-A", "gif", "-w", "1.5", "-e", "robots=off", "https://example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(config.Accept, []string{"jpg", "png", "gif"}) {
+		t.Errorf("unexpected accept list %v", config.Accept)
+	}
+	if time.Duration(config.Wait) != 1500*time.Millisecond {
+		t.Errorf("unexpected wait %v", time.Duration(config.Wait))
+	}
+	if config.Robots {
+		t.Error("expected robots to be disabled")
+	}
+	if config.Tries != DefaultTries || !reflect.DeepEqual(config.RetryOn, DefaultRetryOn) {
+		t.Errorf("unexpected retry defaults %d %v", config.Tries, config.RetryOn)
+	}
+
+	config, err = NewConfig([]string{"-t", "5", "--retry-on", "503,network", "--waitretry", "30", "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Tries != 5 || !reflect.DeepEqual(config.RetryOn, []string{"503", "network"}) || time.Duration(config.WaitRetry) != 30*time.Second {
+		t.Errorf("unexpected retry settings %d %v %v", config.Tries, config.RetryOn, time.Duration(config.WaitRetry))
+	}
+
+	config, err = NewConfig([]string{"--header", "Accept: text/html, */*", "--header", "X-Team: docs", "--referer", "https://portal/", "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config.Headers, []string{"Accept: text/html, */*", "X-Team: docs"}) || config.Referer != "https://portal/" {
+		t.Errorf("unexpected headers %v and referer %q", config.Headers, config.Referer)
+	}
+	if config.MaxRedirect != DefaultMaxRedirect || config.OffsiteRedirect != RedirectsSkip {
+		t.Errorf("unexpected redirect defaults %d %q", config.MaxRedirect, config.OffsiteRedirect)
+	}
+	if config.Compression != CompressionAuto || config.KeepEncoded || config.MaxRatio != DefaultMaxRatio {
+		t.Errorf("unexpected compression defaults %q %v %d", config.Compression, config.KeepEncoded, config.MaxRatio)
+	}
+
+	config, err = NewConfig([]string{"--max-redirect", "0", "--offsite-redirects", "follow", "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxRedirect != 0 || config.OffsiteRedirect != RedirectsFollow {
+		t.Errorf("unexpected redirect settings %d %q", config.MaxRedirect, config.OffsiteRedirect)
+	}
+
+	config, err = NewConfig([]string{"--limit-rate", "200K", "--host-rate", "0.5", "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.LimitRate != 200<<10 || config.HostRate != 0.5 || config.HostBurst != DefaultHostBurst {
+		t.Errorf("unexpected rate settings %d %v %d", config.LimitRate, config.HostRate, config.HostBurst)
+	}
+	for _, args := range [][]string{
+		{"--max-redirect", "-1", "https://example.com"},
+		{"--offsite-redirects", "ask", "https://example.com"},
+		{"--compression", "gzip", "https://example.com"},
+		{"--max-compression-ratio", "-1", "https://example.com"},
+		{"--host-rate", "-2", "https://example.com"},
+	} {
+		if _, err := NewConfig(args); err == nil {
+			t.Errorf("expected error for %v, got nil", args)
+		}
+	}
+}
+
+// TestParseConfigConcurrency тест настроек параллельности
+func TestParseConfigConcurrency(t *testing.T) {
+	config, err := NewConfig([]string{"https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Workers != DefaultWorkers || config.MaxPerHost != DefaultMaxPerHost || config.StorageWorkers != 0 {
+		t.Errorf("unexpected defaults: workers %d, max per host %d, storage workers %d",
+			config.Workers, config.MaxPerHost, config.StorageWorkers)
+	}
+
+	config, err = NewConfig([]string{"--workers", "16", "--max-per-host", "0", "--storage-workers", "2", "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Workers != 16 || config.MaxPerHost != 0 || config.StorageWorkers != 2 {
+		t.Errorf("unexpected values: workers %d, max per host %d, storage workers %d",
+			config.Workers, config.MaxPerHost, config.StorageWorkers)
+	}
+
+	for _, args := range [][]string{
+		{"--workers", "0", "https://example.com"},
+		{"--max-per-host", "-1", "https://example.com"},
+	} {
+		if _, err := NewConfig(args); err == nil {
+			t.Errorf("expected error for %v, got nil", args)
+		}
+	}
+}
+
+// TestByteSize тест разбора размеров
+func TestByteSize(t *testing.T) {
+	tests := map[string]ByteSize{
+		"1024":  1024,
+		"10k":   10 << 10,
+		"500M":  500 << 20,
+		"1.5G":  3 << 29,
+		"2GiB":  2 << 30,
+		"100KB": 100 << 10,
+	}
+	for value, expect := range tests {
+		var b ByteSize
+		if err := b.Set(value); err != nil || b != expect {
+			t.Errorf("%q: expected %d, got %d (%v)", value, expect, b, err)
+		}
+	}
+
+	for _, value := range []string{"", "big", "-1M"} {
+		var b ByteSize
+		if err := b.Set(value); err == nil {
+			t.Errorf("expected error for %q, got %d", value, b)
+		}
+	}
+}
+
+// TestParseConfigJobFile тест профилей файла задания и приоритета флагов
+func TestParseConfigJobFile(t *testing.T) {
+	files := map[string]string{
+		"job.json": `{
+			"directory_prefix": "mirror",
+			"wait": 2,
+			"reject": ["mp4"],
+			"profiles": {
+				"docs": {"urls": ["https://example.com/docs/"], "no_parent": true, "level": 3, "reject": ["zip"]}
+			}
+		}`,
+		"job.toml": `
+			directory_prefix = "mirror"
+			wait = "2s"
+			reject = ["mp4"]
+
+			[profiles.docs]
+			urls = ["https://example.com/docs/"]
+			no_parent = true
+			level = 3
+			reject = ["zip"]
+		`,
+	}
+
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			path := _writeJobFile(t, name, data)
+
+			config, err := NewConfig([]string{"--config", path, "--profile", "docs", "-R", "iso"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(config.URLs, []string{"https://example.com/docs/"}) || !config.NoParent || config.OutputPrefix != "mirror" {
+				t.Errorf("profile values not applied: %+v", config)
+			}
+			if config.Level != 3 || !config.Recursive {
+				t.Errorf("expected level 3 from profile to turn on recursion, got %d %v", config.Level, config.Recursive)
+			}
+			if time.Duration(config.Wait) != 2*time.Second {
+				t.Errorf("unexpected wait %v", time.Duration(config.Wait))
+			}
+			if !reflect.DeepEqual(config.Reject, []string{"iso"}) {
+				t.Errorf("expected command line to override reject list, got %v", config.Reject)
+			}
+		})
+	}
+}
+
+// TestParseConfigJobFileErrors тест ошибок файла задания
+func TestParseConfigJobFileErrors(t *testing.T) {
+	path := _writeJobFile(t, "job.json", `{"urls": ["https://example.com"], "no_such_option": true}`)
+	if _, err := NewConfig([]string{"--config", path}); err == nil {
+		t.Error("expected error for unknown option, got nil")
+	}
+
+	path = _writeJobFile(t, "job.json", `{"urls": ["https://example.com"]}`)
+	if _, err := NewConfig([]string{"--config", path, "--profile", "missing"}); err == nil {
+		t.Error("expected error for missing profile, got nil")
+	}
+
+	if _, err := NewConfig([]string{"--profile", "docs", "https://example.com"}); err == nil {
+		t.Error("expected error for profile without job file, got nil")
+	}
+
+	if _, err := NewConfig([]string{"--bogus", "https://example.com"}); err == nil {
+		t.Error("expected error for unknown flag, got nil")
+	}
+}
+
+// TestParseConfigLogin тест входа через форму из файла задания
+func TestParseConfigLogin(t *testing.T) {
+	path := _writeJobFile(t, "job.toml", `
+		urls = ["https://portal.example.com/"]
+		[login]
+		url = "https://portal.example.com/login"
+		password = "s3cret"
+		success_text = "Sign out"
+		[login.fields]
+		username = "mirror-bot"
+	`)
+	config, err := NewConfig([]string{"--config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := config.Login
+	if login == nil || login.URL != "https://portal.example.com/login" || login.Fields["username"] != "mirror-bot" || login.Secret("") != "s3cret" {
+		t.Fatalf("unexpected login %+v", login)
+	}
+
+	// пароль не попадает в журнал задания и в --print-config
+	var out strings.Builder
+	if err := config.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cret") || !strings.Contains(out.String(), "mirror-bot") {
+		t.Errorf("expected login without password in printed config, got %s", out.String())
+	}
+
+	t.Setenv("PORTAL_PASSWORD", "from-env")
+	if secret := (&Login{PasswordEnv: "PORTAL_PASSWORD"}).Secret("flag"); secret != "from-env" {
+		t.Errorf("expected password from environment, got %q", secret)
+	}
+	if secret := (&Login{}).Secret("flag"); secret != "flag" {
+		t.Errorf("expected --password fallback, got %q", secret)
+	}
+
+	path = _writeJobFile(t, "job.json", `{"urls": ["https://portal.example.com/"], "login": {"url": "/login"}}`)
+	if _, err := NewConfig([]string{"--config", path}); err == nil {
+		t.Error("expected error for relative login url, got nil")
+	}
+}
+
+// TestConfigSeeds тест стартовых URL из аргументов и файла -i
+func TestConfigSeeds(t *testing.T) {
+	path := _writeJobFile(t, "urls.txt", "# seeds\nhttps://b.example.com/\n\n  https://c.example.com/docs/  \n")
+
+	config, err := NewConfig([]string{"-i", path, "https://a.example.com/", "https://a.example.com/blog/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seeds, err := config.Seeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"https://a.example.com/",
+		"https://a.example.com/blog/",
+		"https://b.example.com/",
+		"https://c.example.com/docs/",
+	}
+	if !reflect.DeepEqual(seeds, expect) {
+		t.Errorf("expected seeds %v, got %v", expect, seeds)
+	}
+}
+
+// TestParseConfigAuth тест флагов аутентификации: секреты не попадают в сохраняемую конфигурацию
+func TestParseConfigAuth(t *testing.T) {
+	config, err := NewConfig([]string{
+		"--user", "alice", "--password", "s3cret", "--bearer-token", "t0ken",
+		"--auth-header", "X-Api-Key: a,b", "https://example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.User != "alice" || config.Password != "s3cret" || !reflect.DeepEqual(config.AuthHeaders, []string{"X-Api-Key: a,b"}) {
+		t.Errorf("unexpected auth settings %q %q %v", config.User, config.Password, config.AuthHeaders)
+	}
+
+	var out strings.Builder
+	if err := config.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s3cret", "t0ken", "X-Api-Key"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("expected %q not to be printed", secret)
+		}
+	}
+
+	if _, err := NewConfig([]string{"--ask-password", "--password", "x", "https://example.com"}); err == nil {
+		t.Error("expected error for --ask-password with --password")
+	}
+}
+
+// TestMissingCredentials тест учётных данных, которые нужно снова передать resume
+func TestMissingCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials []string
+		secrets     Secrets
+		expect      []string
+	}{
+		{"none", nil, Secrets{}, nil},
+		{"given", []string{CredentialPassword, CredentialAuthHeader}, Secrets{Password: "x", AuthHeaders: []string{"X-Key: 1"}}, nil},
+		{"ask password", []string{CredentialPassword}, Secrets{AskPassword: true}, nil},
+		{"missing", []string{CredentialPassword, CredentialBearerToken, CredentialProxyPassword}, Secrets{ProxyPassword: "x"}, []string{"--password", "--bearer-token"}},
+		{"login", []string{CredentialLogin}, Secrets{Password: "x"}, nil},
+		{"login missing", []string{CredentialLogin}, Secrets{}, []string{"the login password ($PORTAL_PASSWORD, --password or --ask-password)"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{Credentials: test.credentials, Secrets: test.secrets, Login: &Login{PasswordEnv: "PORTAL_PASSWORD"}}
+			if missing := config.MissingCredentials(); !reflect.DeepEqual(missing, test.expect) {
+				t.Errorf("expected missing %v, got %v", test.expect, missing)
+			}
+		})
+	}
+}
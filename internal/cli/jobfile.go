@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// profilesKey ключ файла задания, под которым описываются именованные профили
+const profilesKey = "profiles"
+
+// loadJobFile читает файл задания и возвращает слои настроек в порядке применения:
+// общие значения верхнего уровня, затем значения выбранного профиля
+func loadJobFile(path, profile string) ([]map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &doc)
+	} else {
+		err = json.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	profiles, _ := doc[profilesKey].(map[string]any)
+	delete(doc, profilesKey)
+	layers := []map[string]any{doc}
+
+	if profile == "" {
+		return layers, nil
+	}
+
+	selected, ok := profiles[profile].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: profile %q not found", path, profile)
+	}
+	return append(layers, selected), nil
+}
+
+// apply накладывает слой настроек из файла задания на конфигурацию.
+// Слой перекодируется в JSON, поэтому JSON и TOML описываются одними тегами Config
+func (c *Config) apply(layer map[string]any) error {
+	data, err := json.Marshal(layer)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(c)
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+)
+
+// Login вход через форму перед обходом. Задаётся только в файле задания, например в TOML:
+//
+//	[login]
+//	url = "https://portal.example.com/login"
+//	success_text = "Sign out"
+//	password_env = "PORTAL_PASSWORD"
+//	[login.fields]
+//	username = "mirror-bot"
+type Login struct {
+	URL           string            `json:"url"`                      // страница с формой входа
+	Form          string            `json:"form,omitempty"`           // id или name формы; по умолчанию форма с полем пароля
+	Fields        map[string]string `json:"fields,omitempty"`         // значения полей формы: логин и прочие
+	PasswordField string            `json:"password_field,omitempty"` // поле пароля, если в форме нет <input type="password">
+	Password      string            `json:"password,omitempty"`       // пароль; в журнал задания не сохраняется
+	PasswordEnv   string            `json:"password_env,omitempty"`   // переменная окружения с паролем
+	SuccessURL    string            `json:"success_url,omitempty"`    // адрес страницы после входа содержит эту строку
+	SuccessText   string            `json:"success_text,omitempty"`   // страница после входа содержит этот текст
+}
+
+// MarshalJSON сохраняет вход без пароля: журнал задания и --print-config его не раскрывают
+func (l Login) MarshalJSON() ([]byte, error) {
+	type plain Login
+	stored := plain(l)
+	stored.Password = ""
+	return json.Marshal(stored)
+}
+
+// Secret пароль для входа: из файла задания, из переменной окружения password_env,
+// иначе password - значение --password или --ask-password
+func (l *Login) Secret(password string) string {
+	switch {
+	case l.Password != "":
+		return l.Password
+	case l.PasswordEnv != "" && os.Getenv(l.PasswordEnv) != "":
+		return os.Getenv(l.PasswordEnv)
+	}
+	return password
+}
+
+// validate проверяет описание входа
+func (l *Login) validate() error {
+	u, err := url.Parse(l.URL)
+	if err != nil || !u.IsAbs() {
+		return errors.New("login: url must be an absolute URL of the login page")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"slices"
+)
+
+// Имена учётных данных в Config.Credentials
+const (
+	CredentialPassword      = "password"
+	CredentialBearerToken   = "bearer-token"
+	CredentialAuthHeader    = "auth-header"
+	CredentialProxyPassword = "proxy-password"
+	CredentialLogin         = "login" // пароль входа через форму из любого источника
+)
+
+// Secrets учётные данные из командной строки. В журнале задания они не сохраняются,
+// поэтому resume принимает те же флаги
+type Secrets struct {
+	Password      string   `json:"-"` // --password: пароль для --user
+	AskPassword   bool     `json:"-"` // --ask-password: запросить пароль в терминале
+	BearerToken   string   `json:"-"` // --bearer-token: токен для заголовка Authorization
+	AuthHeaders   []string `json:"-"` // --auth-header: заголовки с ключами API вида "Name: value"
+	ProxyPassword string   `json:"-"` // --proxy-password: пароль прокси
+}
+
+// RegisterFlags описывает флаги учётных данных
+func (s *Secrets) RegisterFlags(fs *flag.FlagSet) {
+	authHeaders := newRepeatedValue(&s.AuthHeaders)
+
+	fs.StringVar(&s.Password, "password", s.Password, "set password to `PASS`")
+	fs.StringVar(&s.Password, "http-password", s.Password, "set password to `PASS`")
+	fs.BoolVar(&s.AskPassword, "ask-password", s.AskPassword, "prompt for passwords")
+	fs.StringVar(&s.BearerToken, "bearer-token", s.BearerToken, "send `TOKEN` as a bearer token to the origins (scheme, host, port) of the start URLs")
+	fs.Var(authHeaders, "auth-header", "send `HEADER` (\"Name: value\") to the origins of the start URLs only")
+	fs.StringVar(&s.ProxyPassword, "proxy-password", s.ProxyPassword, "set `PASS` as proxy password")
+}
+
+// Validate проверяет сочетание флагов
+func (s *Secrets) Validate() error {
+	if s.AskPassword && s.Password != "" {
+		return errors.New("--ask-password and --password are mutually exclusive")
+	}
+	return nil
+}
+
+// Given имена заданных учётных данных; пароль из --ask-password считается заданным
+func (s *Secrets) Given() []string {
+	var given []string
+	if s.Password != "" || s.AskPassword {
+		given = append(given, CredentialPassword)
+	}
+	if s.BearerToken != "" {
+		given = append(given, CredentialBearerToken)
+	}
+	if len(s.AuthHeaders) > 0 {
+		given = append(given, CredentialAuthHeader)
+	}
+	if s.ProxyPassword != "" {
+		given = append(given, CredentialProxyPassword)
+	}
+	return given
+}
+
+// Given имена учётных данных задания: флаги Secrets и пароль входа через форму
+func (c *Config) Given() []string {
+	given := c.Secrets.Given()
+	if c.Login != nil && c.Login.Secret(c.Password) != "" {
+		given = append(given, CredentialLogin)
+	}
+	return given
+}
+
+// MissingCredentials как задать учётные данные, с которыми задание запускалось (Credentials),
+// но которые не заданы сейчас
+func (c *Config) MissingCredentials() []string {
+	given := c.Given()
+	var missing []string
+	for _, name := range c.Credentials {
+		switch {
+		case slices.Contains(given, name):
+		case name == CredentialLogin:
+			hint := "--password or --ask-password"
+			if c.Login != nil && c.Login.PasswordEnv != "" {
+				hint = "$" + c.Login.PasswordEnv + ", " + hint
+			}
+			missing = append(missing, "the login password ("+hint+")")
+		default:
+			missing = append(missing, "--"+name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,147 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// Коды завершения утилиты
+const (
+	ExitSuccess = 0 // всё прошло успешно
+	ExitError   = 1 // общая ошибка
+	ExitUsage   = 2 // ошибка в аргументах командной строки
+)
+
+// Коды завершения 3-8 (ошибки загрузок по категориям, как у wget) описаны в пакете engine
+
+// DefaultCommand подкоманда, которая выполняется, если подкоманда не указана
+const DefaultCommand = "mirror"
+
+// Command подкоманда утилиты
+type Command struct {
+	Name    string
+	Usage   string
+	Summary string
+	Run     func(args []string) error
+}
+
+// commands список подкоманд утилиты
+var commands = []Command{
+	{Name: "mirror", Usage: "[options] <URL>...", Summary: "download a site (default command)", Run: runMirror},
+	{Name: "resume", Usage: "[options] [DIR]", Summary: "continue an interrupted job stored in DIR", Run: runResume},
+	{Name: "serve", Usage: "[-addr ADDR] [DIR]", Summary: "serve a mirror over HTTP", Run: runServe},
+	{Name: "verify", Usage: "[DIR]", Summary: "check a mirror's files and local links", Run: runVerify},
+	{Name: "diff", Usage: "OLD NEW", Summary: "compare two mirror snapshots", Run: runDiff},
+	{Name: "report", Usage: "[DIR]", Summary: "print crawl statistics of a job", Run: runReport},
+}
+
+// usageError ошибка в аргументах командной строки
+type usageError struct {
+	msg string
+}
+
+// Error реализует интерфейс error
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// exitCoder ошибка с собственным кодом завершения, например задание с неудачными загрузками
+type exitCoder interface {
+	ExitCode() int
+}
+
+// errFailed ошибка, о которой подкоманда уже сообщила пользователю сама
+var errFailed = errors.New("failed")
+
+// Run выполняет подкоманду, заданную первым аргументом, и возвращает код завершения.
+// Если первый аргумент не подкоманда, все аргументы передаются подкоманде mirror
+func Run(args []string) int {
+	name := DefaultCommand
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			printUsage(os.Stdout)
+			return ExitSuccess
+		}
+		if _, ok := findCommand(args[0]); ok {
+			name, args = args[0], args[1:]
+		}
+	}
+
+	cmd, _ := findCommand(name)
+	err := cmd.Run(args)
+
+	var ue *usageError
+	var ce exitCoder
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		// по -h описание флагов уже выведено
+		return ExitSuccess
+	case errors.As(err, &ue):
+		fmt.Fprintf(os.Stderr, "mirror-wget %s: %v\nusage: mirror-wget %s %s\n", cmd.Name, err, cmd.Name, cmd.Usage)
+		return ExitUsage
+	case errors.Is(err, errFailed):
+		return ExitError
+	case errors.As(err, &ce):
+		fmt.Fprintf(os.Stderr, "mirror-wget %s: %v\n", cmd.Name, err)
+		return ce.ExitCode()
+	default:
+		fmt.Fprintf(os.Stderr, "mirror-wget %s: %v\n", cmd.Name, err)
+		return ExitError
+	}
+}
+
+// findCommand ищет подкоманду по имени
+func findCommand(name string) (Command, bool) {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// printUsage выводит список подкоманд
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: mirror-wget <command> [arguments]")
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", cmd.Name, cmd.Usage, cmd.Summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'mirror-wget <command> -h' for the options of a command.")
+}
+
+// parseFlags разбирает флаги подкоманды. Ошибка разбора возвращается как usageError, чтобы Run
+// вывел её с кодом ExitUsage; по -h описание флагов выводится в stdout и возвращается flag.ErrHelp
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(io.Discard)
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+		return err
+	}
+	if err != nil {
+		return &usageError{msg: err.Error()}
+	}
+	return nil
+}
+
+// dirArg возвращает каталог зеркала из необязательного позиционного аргумента
+func dirArg(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return ".", nil
+	case 1:
+		return args[0], nil
+	default:
+		return "", &usageError{msg: "too many arguments"}
+	}
+}
@@ -0,0 +1,26 @@
+package command
+
+import "testing"
+
+// TestRunFlagErrors тест: ошибка разбора флагов любой подкоманды завершается кодом ExitUsage, -h - ExitSuccess
+func TestRunFlagErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"--bogus", "https://example.com"}, ExitUsage},
+		{[]string{"mirror", "--bogus", "https://example.com"}, ExitUsage},
+		{[]string{"resume", "--bogus"}, ExitUsage},
+		{[]string{"serve", "--bogus"}, ExitUsage},
+		{[]string{"verify", "--bogus"}, ExitUsage},
+		{[]string{"diff", "--bogus", "a", "b"}, ExitUsage},
+		{[]string{"report", "--bogus"}, ExitUsage},
+		{[]string{"mirror", "-h"}, ExitSuccess},
+		{[]string{"report", "-h"}, ExitSuccess},
+	}
+	for _, tt := range tests {
+		if code := Run(tt.args); code != tt.code {
+			t.Errorf("Run(%q) = %d, want %d", tt.args, code, tt.code)
+		}
+	}
+}
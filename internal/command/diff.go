@@ -0,0 +1,32 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"mirror-wget/internal/snapshot"
+)
+
+// runDiff сравнивает два снимка зеркала; при наличии различий завершается с ExitError, как diff(1)
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		return &usageError{msg: "expected two snapshot directories"}
+	}
+
+	changes, err := snapshot.Diff(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		fmt.Printf("%s %s\n", change.Kind, change.Path)
+	}
+	if len(changes) > 0 {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"mirror-wget/internal/cli"
+	"mirror-wget/internal/engine"
+	"mirror-wget/internal/logging"
+	"os"
+)
+
+// runMirror скачивает сайт по конфигурации из флагов и файла задания
+func runMirror(args []string) error {
+	config, err := cli.NewConfig(args)
+	if errors.Is(err, flag.ErrHelp) {
+		cli.PrintUsage(os.Stdout)
+		return err
+	}
+	if err != nil {
+		return &usageError{msg: err.Error()}
+	}
+
+	if config.PrintConfig {
+		return config.Print(os.Stdout)
+	}
+	// пароль запрашивается до запуска прогресса, который занимает терминал
+	if config.AskPassword {
+		if config.Password, err = askPassword(config.User); err != nil {
+			return err
+		}
+	}
+
+	stderr, tracker, stopProgress := startProgress(!config.NoProgress && !config.Quiet)
+	defer stopProgress()
+
+	closeLog, err := logging.Setup(config.Options, stderr)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	return engine.Handle(config, tracker)
+}
@@ -0,0 +1,30 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"os"
+)
+
+// askPassword запрашивает пароль в терминале без эха. Терминал открывается напрямую,
+// потому что стандартный ввод может быть занят списком URL (-i -)
+func askPassword(user string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", errors.New("--ask-password requires a terminal")
+	}
+	defer tty.Close()
+
+	prompt := "Password: "
+	if user != "" {
+		prompt = fmt.Sprintf("Password for user %q: ", user)
+	}
+	fmt.Fprint(tty, prompt)
+	password, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("read password: %v", err)
+	}
+	return string(password), nil
+}
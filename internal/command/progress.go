@@ -0,0 +1,20 @@
+package command
+
+import (
+	"io"
+	"mirror-wget/internal/progress"
+	"os"
+)
+
+// startProgress включает отображение прогресса, если stderr - терминал.
+// Возвращает writer для журнала, Tracker (nil без прогресса) и функцию, убирающую прогресс с экрана
+func startProgress(enabled bool) (io.Writer, *progress.Tracker, func()) {
+	if !enabled || !progress.IsTerminal(os.Stderr) {
+		return os.Stderr, nil, func() {}
+	}
+
+	tracker := progress.NewTracker()
+	display := progress.NewDisplay(os.Stderr, tracker)
+	display.Start()
+	return display, tracker, display.Stop
+}
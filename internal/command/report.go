@@ -0,0 +1,72 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"mirror-wget/internal/journal"
+	"os"
+	"text/tabwriter"
+)
+
+// runReport выводит статистику обхода по журналу задания
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	dir, err := dirArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	state, err := journal.Load(dir)
+	if err != nil {
+		return fmt.Errorf("no job journal in %q: %v", dir, err)
+	}
+	stats := state.Stats()
+
+	status := "interrupted"
+	if state.Finished {
+		status = "finished"
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Job:\t%s (%s)\n", dir, status)
+	fmt.Fprintf(tw, "Queued:\t%d\n", stats.Queued)
+	fmt.Fprintf(tw, "Downloaded:\t%d (saved %d)\n", stats.Done, stats.Saved)
+	fmt.Fprintf(tw, "Failed:\t%d\n", stats.Failed)
+	fmt.Fprintf(tw, "Skipped:\t%d\n", stats.Skipped)
+	fmt.Fprintf(tw, "Pending:\t%d\n", len(state.Pending))
+	fmt.Fprintf(tw, "Bytes:\t%d\n", stats.Bytes)
+	fmt.Fprintf(tw, "Elapsed:\t%s\n", stats.Elapsed)
+	tw.Flush()
+
+	printBuckets("Content type", stats.ByContentType)
+	printBuckets("Depth", stats.ByDepth)
+
+	if len(stats.Failures) > 0 {
+		fmt.Println()
+		fmt.Println("Failures:")
+		for _, entry := range stats.Failures {
+			fmt.Printf("  %s: %s\n", entry.URL, entry.Error)
+		}
+	}
+
+	return nil
+}
+
+// printBuckets выводит таблицу групп документов
+func printBuckets(title string, buckets []journal.Bucket) {
+	if len(buckets) == 0 {
+		return
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tFiles\tBytes\t\n", title)
+	for _, b := range buckets {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t\n", b.Key, b.Count, b.Bytes)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"flag"
+	"mirror-wget/internal/cli"
+	"mirror-wget/internal/engine"
+	"mirror-wget/internal/logging"
+)
+
+// runResume продолжает прерванное задание
+func runResume(args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ContinueOnError)
+	var logOpts logging.Options
+	logOpts.RegisterFlags(fs)
+	// пароли и токены не сохраняются в журнале задания, поэтому передаются снова
+	var secrets cli.Secrets
+	secrets.RegisterFlags(fs)
+	noProgress := fs.Bool("no-progress", false, "don't show live progress on a terminal")
+	retryFailed := fs.Bool("retry-failed", false, "download again all failed URLs, not only those matching --retry-on of the job")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	dir, err := dirArg(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := logOpts.Validate(); err != nil {
+		return &usageError{msg: err.Error()}
+	}
+	if err := secrets.Validate(); err != nil {
+		return &usageError{msg: err.Error()}
+	}
+	if secrets.AskPassword {
+		if secrets.Password, err = askPassword(""); err != nil {
+			return err
+		}
+	}
+
+	stderr, tracker, stopProgress := startProgress(!*noProgress && !logOpts.Quiet)
+	defer stopProgress()
+
+	closeLog, err := logging.Setup(logOpts, stderr)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	return engine.Resume(dir, engine.ResumeOptions{Secrets: secrets, RetryFailed: *retryFailed}, tracker)
+}
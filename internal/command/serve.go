@@ -0,0 +1,49 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"mirror-wget/internal/journal"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// DefaultServeAddr адрес, на котором по умолчанию раздаётся зеркало
+const DefaultServeAddr = "127.0.0.1:8000"
+
+// runServe раздаёт зеркало по HTTP
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", DefaultServeAddr, "listen on `ADDR`")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	dir, err := dirArg(fs.Args())
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", dir)
+	}
+
+	fmt.Printf("Serving %s on http://%s/\n", dir, *addr)
+	return http.ListenAndServe(*addr, mirrorHandler(dir))
+}
+
+// mirrorHandler раздаёт файлы зеркала, скрывая каталог состояния задания в корне.
+// Путь сравнивается после path.Clean, как его разрешит http.FileServer, поэтому
+// "/a/../.mirror-wget/" скрыт, а страница "/docs/.mirror-wget-notes.html" раздаётся
+func mirrorHandler(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clean := path.Clean("/" + r.URL.Path)
+		if clean == "/"+journal.DirName || strings.HasPrefix(clean, "/"+journal.DirName+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"mirror-wget/internal/journal"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMirrorHandler тест: каталог состояния задания скрыт, остальные файлы раздаются
+func TestMirrorHandler(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, journal.DirName), 0o755)
+	os.WriteFile(filepath.Join(dir, journal.DirName, "journal.jsonl"), []byte("{}"), 0o644)
+	os.MkdirAll(filepath.Join(dir, "docs", journal.DirName+"-notes"), 0o755)
+	os.WriteFile(filepath.Join(dir, "docs", journal.DirName+"-notes", "index.html"), []byte("notes"), 0o644)
+	os.WriteFile(filepath.Join(dir, "docs", journal.DirName+".html"), []byte("page"), 0o644)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/" + journal.DirName, http.StatusNotFound},
+		{"/" + journal.DirName + "/", http.StatusNotFound},
+		{"/" + journal.DirName + "/journal.jsonl", http.StatusNotFound},
+		{"/docs/../" + journal.DirName + "/journal.jsonl", http.StatusNotFound},
+		{"//" + journal.DirName + "/journal.jsonl", http.StatusNotFound},
+		{"/docs/" + journal.DirName + ".html", http.StatusOK},
+		{"/docs/" + journal.DirName + "-notes/", http.StatusOK},
+	}
+	handler := mirrorHandler(dir)
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path = tt.path
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, w.Code)
+		}
+	}
+}
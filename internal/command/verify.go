@@ -0,0 +1,51 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"mirror-wget/internal/journal"
+	"mirror-wget/internal/snapshot"
+)
+
+// runVerify проверяет целостность файлов зеркала и его локальные ссылки
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	dir, err := dirArg(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var problems []snapshot.Problem
+	state, err := journal.Load(dir)
+	switch {
+	case err == nil:
+		problems = append(problems, snapshot.VerifyFiles(state)...)
+	case errors.Is(err, fs.ErrNotExist):
+		fmt.Printf("No job journal in %s, checking links only\n", dir)
+	default:
+		return err
+	}
+
+	linkProblems, err := snapshot.VerifyLinks(dir)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, linkProblems...)
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problems found\n", len(problems))
+		return errFailed
+	}
+
+	fmt.Println("OK")
+	return nil
+}
@@ -0,0 +1,150 @@
+package cookies
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpOnlyPrefix префикс строки cookies.txt для HttpOnly cookies (формат curl и браузеров)
+const httpOnlyPrefix = "#HttpOnly_"
+
+// header первая строка файла, по которой его узнают curl и wget
+const header = "# Netscape HTTP Cookie File\n"
+
+// Load добавляет в хранилище cookies из файла в формате Netscape cookies.txt.
+// Сессионные cookies (срок 0) загружаются, истёкшие - пропускаются
+func (j *Jar) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return j.Read(f)
+}
+
+// Read читает cookies в формате Netscape cookies.txt
+func (j *Jar) Read(r io.Reader) error {
+	now := time.Now()
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		e, err := parseLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		if e == nil || e.expired(now) {
+			continue
+		}
+
+		j.mu.Lock()
+		j.entries[e.key()] = e
+		j.mu.Unlock()
+	}
+	return scanner.Err()
+}
+
+// parseLine разбирает строку cookies.txt; для пустых строк и комментариев возвращает nil
+func parseLine(line string) (*entry, error) {
+	line = strings.TrimRight(line, "\r")
+	httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+	line = strings.TrimPrefix(line, httpOnlyPrefix)
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	fields := strings.Split(line, "\t")
+	if len(fields) == 6 {
+		// значение может быть пустым, и тогда завершающий таб теряется
+		fields = append(fields, "")
+	}
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("expected 7 tab-separated fields, got %d", len(fields))
+	}
+
+	expires, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry %q", fields[4])
+	}
+
+	e := &entry{
+		Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+		HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+		Path:     fields[2],
+		Secure:   strings.EqualFold(fields[3], "TRUE"),
+		HttpOnly: httpOnly,
+		Name:     fields[5],
+		Value:    fields[6],
+	}
+	if e.Domain == "" {
+		return nil, fmt.Errorf("empty domain")
+	}
+	if e.Path == "" {
+		e.Path = "/"
+	}
+	if expires > 0 {
+		e.Expires = time.Unix(expires, 0)
+	}
+	return e, nil
+}
+
+// Save сохраняет cookies в файл в формате Netscape cookies.txt.
+// Сессионные cookies сохраняются, только если keepSession
+func (j *Jar) Save(path string, keepSession bool) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := j.Write(f, keepSession); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write записывает cookies в формате Netscape cookies.txt
+func (j *Jar) Write(w io.Writer, keepSession bool) error {
+	now := time.Now()
+
+	j.mu.Lock()
+	entries := make([]*entry, 0, len(j.entries))
+	for _, e := range j.entries {
+		if e.expired(now) || e.Expires.IsZero() && !keepSession {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	j.mu.Unlock()
+	sort.Slice(entries, func(a, b int) bool { return entries[a].key() < entries[b].key() })
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(header)
+	for _, e := range entries {
+		domain := e.Domain
+		if !e.HostOnly {
+			domain = "." + domain
+		}
+		if e.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !e.Expires.IsZero() {
+			expires = e.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, boolField(!e.HostOnly), e.Path, boolField(e.Secure), expires, e.Name, e.Value)
+	}
+	return bw.Flush()
+}
+
+// boolField логическое значение в формате cookies.txt
+func boolField(v bool) string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,200 @@
+package cookies
+
+import (
+	"errors"
+	"golang.org/x/net/publicsuffix"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Jar хранилище cookies, общее для всех воркеров. В отличие от net/http/cookiejar
+// позволяет перечислить все cookies, чтобы сохранить их в cookies.txt
+type Jar struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry cookie со всеми атрибутами, нужными для отбора и сохранения
+type entry struct {
+	Name     string
+	Value    string
+	Domain   string // без ведущей точки, в нижнем регистре
+	Path     string
+	HostOnly bool // cookie без атрибута Domain отправляется только на тот же хост
+	Secure   bool
+	HttpOnly bool
+	Expires  time.Time // нулевое значение - сессионная cookie
+}
+
+// NewJar инициализирует Jar
+func NewJar() *Jar {
+	return &Jar{entries: make(map[string]*entry)}
+}
+
+// key ключ cookie: имя уникально в пределах домена и пути
+func (e *entry) key() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+// expired истёк ли срок действия cookie к моменту now
+func (e *entry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+// SetCookies реализует http.CookieJar
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		e, ok := newEntry(c, host, u.Path, now)
+		if !ok {
+			continue
+		}
+		if e.expired(now) {
+			delete(j.entries, e.key())
+			continue
+		}
+		j.entries[e.key()] = e
+	}
+}
+
+// newEntry проверяет cookie, полученную от host, и заполняет атрибуты по умолчанию
+func newEntry(c *http.Cookie, host, requestPath string, now time.Time) (*entry, bool) {
+	e := &entry{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	switch {
+	case domain == "" || domain == host:
+		e.Domain, e.HostOnly = host, c.Domain == ""
+	case net.ParseIP(host) != nil || !domainMatch(host, domain):
+		return nil, false
+	default:
+		// cookie нельзя выставить на весь публичный суффикс, например co.uk
+		if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+			return nil, false
+		}
+		e.Domain = domain
+	}
+
+	if !strings.HasPrefix(e.Path, "/") {
+		e.Path = defaultPath(requestPath)
+	}
+
+	switch {
+	case c.MaxAge < 0:
+		e.Expires = now
+	case c.MaxAge > 0:
+		e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+	case !c.Expires.IsZero():
+		e.Expires = c.Expires
+	}
+	return e, true
+}
+
+// Cookies реализует http.CookieJar
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return nil
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+
+	j.mu.Lock()
+	var selected []*entry
+	for key, e := range j.entries {
+		if e.expired(now) {
+			delete(j.entries, key)
+			continue
+		}
+		if e.Secure && u.Scheme != "https" {
+			continue
+		}
+		if e.HostOnly && host != e.Domain || !e.HostOnly && !domainMatch(host, e.Domain) {
+			continue
+		}
+		if !pathMatch(path, e.Path) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+	j.mu.Unlock()
+
+	// более точные пути отправляются первыми
+	sort.Slice(selected, func(a, b int) bool {
+		if len(selected[a].Path) != len(selected[b].Path) {
+			return len(selected[a].Path) > len(selected[b].Path)
+		}
+		return selected[a].Name < selected[b].Name
+	})
+
+	cookies := make([]*http.Cookie, 0, len(selected))
+	for _, e := range selected {
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value})
+	}
+	return cookies
+}
+
+// Len число cookies в хранилище
+func (j *Jar) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.entries)
+}
+
+// canonicalHost хост без порта в нижнем регистре
+func canonicalHost(host string) (string, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", errors.New("empty host")
+	}
+	return host, nil
+}
+
+// domainMatch совпадает ли host с domain или является его поддоменом
+func domainMatch(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// pathMatch входит ли path запроса в путь cookie
+func pathMatch(path, cookiePath string) bool {
+	if path == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(path, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || path[len(cookiePath)] == '/'
+}
+
+// defaultPath путь cookie по умолчанию - каталог пути запроса
+func defaultPath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
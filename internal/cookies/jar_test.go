@@ -0,0 +1,105 @@
+package cookies
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func _mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// _names имена cookies в порядке отправки
+func _names(cookies []*http.Cookie) []string {
+	names := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// TestJarMatching тест отбора cookies по домену, пути, Secure и сроку действия
+func TestJarMatching(t *testing.T) {
+	jar := NewJar()
+	jar.SetCookies(_mustParse(t, "https://www.example.com/docs/page.html"), []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "1", Domain: ".example.com", Path: "/"},
+		{Name: "docs", Value: "1", Path: "/docs"},
+		{Name: "secure", Value: "1", Path: "/", Secure: true},
+		{Name: "expired", Value: "1", MaxAge: -1},
+		{Name: "public", Value: "1", Domain: "com"},
+		{Name: "foreign", Value: "1", Domain: "other.org"},
+	})
+
+	tests := []struct {
+		url      string
+		expected []string
+	}{
+		{"https://www.example.com/docs/v2/", []string{"docs", "host", "domain", "secure"}},
+		{"http://www.example.com/docs", []string{"docs", "host", "domain"}},
+		{"https://www.example.com/docsets/", []string{"domain", "secure"}},
+		{"https://api.example.com/docs/", []string{"domain"}},
+		{"https://example.org/", []string{}},
+	}
+	for _, test := range tests {
+		got := _names(jar.Cookies(_mustParse(t, test.url)))
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.url, test.expected, got)
+		}
+	}
+}
+
+// TestCookiesTxt тест чтения и записи Netscape cookies.txt
+func TestCookiesTxt(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	input := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"",
+		".example.com\tTRUE\t/\tFALSE\t" + strconv.FormatInt(expires, 10) + "\tsid\tabc",
+		"#HttpOnly_example.com\tFALSE\t/account\tTRUE\t0\ttoken\txyz",
+		"example.com\tFALSE\t/\tFALSE\t1\told\tgone",
+		"example.com\tFALSE\t/\tFALSE\t0\tempty",
+	}, "\n")
+
+	jar := NewJar()
+	if err := jar.Read(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	got := _names(jar.Cookies(_mustParse(t, "https://example.com/account/")))
+	if expected := []string{"token", "empty", "sid"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := _names(jar.Cookies(_mustParse(t, "http://sub.example.com/"))); !reflect.DeepEqual(got, []string{"sid"}) {
+		t.Errorf("expected only domain cookie for subdomain, got %v", got)
+	}
+
+	var out bytes.Buffer
+	if err := jar.Write(&out, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Netscape HTTP Cookie File\n.example.com\tTRUE\t/\tFALSE\t" + strconv.FormatInt(expires, 10) + "\tsid\tabc\n"
+	if out.String() != expected {
+		t.Errorf("expected only persistent cookies\n%q, got\n%q", expected, out.String())
+	}
+
+	out.Reset()
+	jar.Write(&out, true)
+	if !strings.Contains(out.String(), "#HttpOnly_example.com\tFALSE\t/account\tTRUE\t0\ttoken\txyz\n") {
+		t.Errorf("expected session cookie to be kept, got\n%s", out.String())
+	}
+
+	if err := NewJar().Read(strings.NewReader("example.com\tFALSE\t/")); err == nil {
+		t.Error("expected error for malformed line")
+	}
+}
@@ -0,0 +1,305 @@
+package downloader
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Auth учётные данные. User, Password, Token и Headers отправляются только origin из
+// Origins (схема, хост и порт стартовых URL), поэтому не уходят на сторонние сайты
+// при редиректах и не уходят по http, если стартовый URL был https.
+// Учётные данные из .netrc отправляются только хосту своей записи machine,
+// а из записи default - только origin из Origins
+type Auth struct {
+	User        string
+	Password    string
+	Token       string      // bearer токен, заголовок Authorization: Bearer
+	Headers     http.Header // заголовки с ключами API
+	Origins     []string    // origin (см. Origin), которым отправляются учётные данные
+	Netrc       *Netrc
+	NoChallenge bool // отправлять Basic сразу, не дожидаясь ответа 401
+}
+
+// Origin схема, хост и порт u в виде "https://example.com:443"; порт по умолчанию
+// подставляется, чтобы https://example.com и https://example.com:443 совпадали
+func Origin(u *url.URL) string {
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		switch scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+// scoped входит ли u в origin, которым отправляются учётные данные из командной строки
+func (a *Auth) scoped(u *url.URL) bool {
+	origin := Origin(u)
+	for _, o := range a.Origins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// credentials логин и пароль для u: из командной строки или из .netrc
+func (a *Auth) credentials(u *url.URL) (string, string, bool) {
+	if a.User != "" && a.scoped(u) {
+		return a.User, a.Password, true
+	}
+	// запись default не привязана к хосту, поэтому, как и учётные данные
+	// из командной строки, отправляется только origin стартовых URL
+	m, ok := a.Netrc.Machine(u.Hostname())
+	if !ok && a.scoped(u) {
+		m, ok = a.Netrc.Lookup(u.Hostname())
+	}
+	if ok && m.Login != "" {
+		return m.Login, m.Password, true
+	}
+	return "", "", false
+}
+
+// downgraded перешла ли цепочка редиректов req с https на http: по открытому
+// каналу учётные данные не отправляются, даже если хост тот же
+func downgraded(req *http.Request) bool {
+	first := req
+	for first.Response != nil && first.Response.Request != nil {
+		first = first.Response.Request
+	}
+	return req.URL.Scheme == "http" && first.URL.Scheme == "https"
+}
+
+// authTransport добавляет учётные данные к запросам и отвечает на запросы
+// аутентификации Basic и Digest. Принятая схема запоминается для origin,
+// и следующие запросы к нему сразу отправляются с заголовком Authorization
+type authTransport struct {
+	next       http.RoundTripper
+	auth       *Auth
+	mu         sync.Mutex
+	challenges map[string]*challenge
+}
+
+// newAuthTransport оборачивает next; если учётных данных нет, возвращает next
+func newAuthTransport(next http.RoundTripper, auth *Auth) http.RoundTripper {
+	if auth == nil {
+		return next
+	}
+	return &authTransport{next: next, auth: auth, challenges: make(map[string]*challenge)}
+}
+
+// RoundTrip реализует http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if downgraded(req) {
+		return t.next.RoundTrip(req)
+	}
+	scoped := t.auth.scoped(req.URL)
+	user, password, hasCredentials := t.auth.credentials(req.URL)
+	if !scoped && !hasCredentials {
+		return t.next.RoundTrip(req)
+	}
+
+	// RoundTripper не должен менять исходный запрос
+	req = req.Clone(req.Context())
+	if scoped {
+		for name, values := range t.auth.Headers {
+			req.Header[name] = values
+		}
+		if t.auth.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+t.auth.Token)
+			return t.next.RoundTrip(req)
+		}
+	}
+	if !hasCredentials {
+		return t.next.RoundTrip(req)
+	}
+
+	t.mu.Lock()
+	ch := t.challenges[Origin(req.URL)]
+	t.mu.Unlock()
+	switch {
+	case ch != nil:
+		req.Header.Set("Authorization", ch.authorization(req, user, password))
+	case t.auth.NoChallenge:
+		req.SetBasicAuth(user, password)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.Body != nil && req.Body != http.NoBody {
+		return resp, err
+	}
+
+	next := parseChallenge(resp.Header.Values("WWW-Authenticate"))
+	// учётные данные уже отправлены по этой схеме и отклонены: повтор не поможет
+	if next == nil || req.Header.Get("Authorization") != "" && !next.stale && (ch == nil || ch.scheme == next.scheme) {
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	t.mu.Lock()
+	t.challenges[Origin(req.URL)] = next
+	t.mu.Unlock()
+
+	retry := req.Clone(req.Context())
+	retry.Header.Set("Authorization", next.authorization(retry, user, password))
+	return t.next.RoundTrip(retry)
+}
+
+// challenge запрос аутентификации сервера из WWW-Authenticate
+type challenge struct {
+	scheme    string // basic или digest
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string // auth, если сервер его поддерживает
+	stale     bool
+	count     atomic.Uint32 // счётчик nc для nonce
+}
+
+// parseChallenge выбирает из заголовков WWW-Authenticate Digest, иначе Basic
+func parseChallenge(headers []string) *challenge {
+	var basic *challenge
+	for _, header := range headers {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+		switch strings.ToLower(scheme) {
+		case "digest":
+			params := parseAuthParams(rest)
+			ch := &challenge{
+				scheme:    "digest",
+				realm:     params["realm"],
+				nonce:     params["nonce"],
+				opaque:    params["opaque"],
+				algorithm: params["algorithm"],
+				stale:     strings.EqualFold(params["stale"], "true"),
+			}
+			for _, qop := range strings.Split(params["qop"], ",") {
+				if strings.TrimSpace(qop) == "auth" {
+					ch.qop = "auth"
+				}
+			}
+			if ch.nonce != "" && ch.newHash() != nil {
+				return ch
+			}
+		case "basic":
+			basic = &challenge{scheme: "basic", realm: parseAuthParams(rest)["realm"]}
+		}
+	}
+	return basic
+}
+
+// parseAuthParams разбирает параметры вида name="value", name=value
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(strings.TrimSpace(s), ",") {
+		s = strings.TrimSpace(s)
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimSpace(rest)
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			value, s = b.String(), rest[min(i+1, len(rest)):]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[name] = value
+	}
+	return params
+}
+
+// newHash хеш-функция алгоритма Digest; nil для неподдерживаемых алгоритмов
+func (c *challenge) newHash() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+	case "", "MD5":
+		return md5.New()
+	case "SHA-256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// authorization значение заголовка Authorization для запроса req
+func (c *challenge) authorization(req *http.Request, user, password string) string {
+	if c.scheme == "basic" {
+		r := &http.Request{Header: make(http.Header)}
+		r.SetBasicAuth(user, password)
+		return r.Header.Get("Authorization")
+	}
+
+	h := func(parts ...string) string {
+		hh := c.newHash()
+		hh.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	uri := req.URL.RequestURI()
+	cnonce := newCnonce()
+	nc := fmt.Sprintf("%08x", c.count.Add(1))
+
+	ha1 := h(user, c.realm, password)
+	if strings.HasSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+	ha2 := h(req.Method, uri)
+
+	var response string
+	if c.qop == "auth" {
+		response = h(ha1, c.nonce, nc, cnonce, c.qop, ha2)
+	} else {
+		response = h(ha1, c.nonce, ha2)
+	}
+
+	fields := []string{
+		fmt.Sprintf("username=%q", user),
+		fmt.Sprintf("realm=%q", c.realm),
+		fmt.Sprintf("nonce=%q", c.nonce),
+		fmt.Sprintf("uri=%q", uri),
+		fmt.Sprintf("response=%q", response),
+	}
+	if c.algorithm != "" {
+		fields = append(fields, "algorithm="+c.algorithm)
+	}
+	if c.opaque != "" {
+		fields = append(fields, fmt.Sprintf("opaque=%q", c.opaque))
+	}
+	if c.qop == "auth" {
+		fields = append(fields, "qop=auth", "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	return "Digest " + strings.Join(fields, ", ")
+}
+
+// newCnonce случайный cnonce клиента
+func newCnonce() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
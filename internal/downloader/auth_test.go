@@ -0,0 +1,292 @@
+package downloader
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestParseNetrc тест разбора .netrc
+func TestParseNetrc(t *testing.T) {
+	netrc, err := ParseNetrc(strings.NewReader(`
+# internal portals
+machine docs.example.com login alice password s3cret
+machine wiki.example.com
+	login bob
+	password "hunter2"
+macdef init
+machine fake.example.com login mallory password x
+
+machine docs.example.com login ignored password ignored
+default login anonymous password guest
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host  string
+		login string
+		pass  string
+	}{
+		{"docs.example.com", "alice", "s3cret"},
+		{"WIKI.example.com", "bob", `"hunter2"`},
+		{"fake.example.com", "anonymous", "guest"},
+		{"other.org", "anonymous", "guest"},
+	}
+	for _, test := range tests {
+		m, ok := netrc.Lookup(test.host)
+		if !ok || m.Login != test.login || m.Password != test.pass {
+			t.Errorf("%s: expected %s/%s, got %+v", test.host, test.login, test.pass, m)
+		}
+	}
+
+	var empty *Netrc
+	if _, ok := empty.Lookup("docs.example.com"); ok {
+		t.Error("expected nil Netrc to have no credentials")
+	}
+}
+
+// TestAuthBasic тест Basic аутентификации после запроса 401
+func TestAuthBasic(t *testing.T) {
+	var unauthorized atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "alice" || pass != "s3cret" {
+			unauthorized.Add(1)
+			w.Header().Set("WWW-Authenticate", `Basic realm="docs"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	client := NewClient(Options{Auth: &Auth{User: "alice", Password: "s3cret", Origins: []string{Origin(u)}}})
+	for _, path := range []string{"/", "/a", "/b"} {
+		resp, err := client.Get(context.Background(), srv.URL+path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	// после первого запроса учётные данные отправляются сразу
+	if n := unauthorized.Load(); n != 1 {
+		t.Errorf("expected 1 challenge, got %d", n)
+	}
+
+	wrong := NewClient(Options{Auth: &Auth{User: "alice", Password: "wrong", Origins: []string{Origin(u)}}})
+	_, err := wrong.Get(context.Background(), srv.URL)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for wrong password, got %v", err)
+	}
+}
+
+// TestAuthDigest тест ответа на запрос Digest аутентификации с qop=auth
+func TestAuthDigest(t *testing.T) {
+	const realm, nonce = "portal", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Digest ") {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm=%q, qop="auth,auth-int", nonce=%q, opaque="5ccc"`, realm, nonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p := parseAuthParams(strings.TrimPrefix(header, "Digest "))
+		ha1 := md5hex("alice:" + realm + ":s3cret")
+		ha2 := md5hex(r.Method + ":" + p["uri"])
+		expected := md5hex(strings.Join([]string{ha1, nonce, p["nc"], p["cnonce"], "auth", ha2}, ":"))
+		if p["username"] != "alice" || p["uri"] != r.URL.RequestURI() || p["opaque"] != "5ccc" || p["response"] != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	netrc, _ := ParseNetrc(strings.NewReader("machine 127.0.0.1 login alice password s3cret"))
+	client := NewClient(Options{Auth: &Auth{Netrc: netrc}})
+	for _, path := range []string{"/docs/?page=1", "/docs/2"} {
+		resp, err := client.Get(context.Background(), srv.URL+path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+}
+
+// TestAuthScope тест: учётные данные не уходят на другой хост при редиректе
+func TestAuthScope(t *testing.T) {
+	var leaked atomic.Bool
+	offsite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("X-Api-Key") != "" {
+			leaked.Store(true)
+		}
+	}))
+	defer offsite.Close()
+	// другой хост того же сервера: localhost вместо 127.0.0.1
+	offsiteURL := strings.Replace(offsite.URL, "127.0.0.1", "localhost", 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" || r.Header.Get("X-Api-Key") != "k3y" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, offsiteURL+"/elsewhere", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	client := NewClient(Options{Auth: &Auth{
+		Token:   "t0ken",
+		Headers: http.Header{"X-Api-Key": {"k3y"}},
+		Origins: []string{Origin(u)},
+	}})
+	resp, err := client.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if leaked.Load() {
+		t.Error("credentials leaked to off-site redirect")
+	}
+}
+
+// TestAuthNetrcDefault тест: запись default из .netrc не уходит на сторонний хост,
+// даже если он запрашивает аутентификацию
+func TestAuthNetrcDefault(t *testing.T) {
+	var leaked atomic.Bool
+	offsite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			leaked.Store(true)
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="offsite"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer offsite.Close()
+	// другой хост того же сервера: localhost вместо 127.0.0.1
+	offsiteURL := strings.Replace(offsite.URL, "127.0.0.1", "localhost", 1)
+
+	var authorized atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="docs"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		authorized.Store(true)
+		http.Redirect(w, r, offsiteURL+"/elsewhere", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	netrc, _ := ParseNetrc(strings.NewReader("default login alice password s3cret"))
+	u, _ := url.Parse(srv.URL)
+	for _, noChallenge := range []bool{false, true} {
+		leaked.Store(false)
+		authorized.Store(false)
+		client := NewClient(Options{Auth: &Auth{Netrc: netrc, Origins: []string{Origin(u)}, NoChallenge: noChallenge}})
+		_, err := client.Get(context.Background(), srv.URL)
+		var statusErr *StatusError
+		// стартовый хост принял учётные данные, 401 ответил сторонний
+		if !authorized.Load() || !errors.As(err, &statusErr) || statusErr.Code != http.StatusUnauthorized {
+			t.Errorf("no challenge %v: expected 401 from off-site host, got %v", noChallenge, err)
+		}
+		if leaked.Load() {
+			t.Errorf("no challenge %v: .netrc default credentials leaked to off-site host", noChallenge)
+		}
+	}
+}
+
+// TestAuthOrigin тест: учётные данные не уходят на другой порт того же хоста
+// и по http после редиректа с https
+func TestAuthOrigin(t *testing.T) {
+	var leaked atomic.Bool
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			leaked.Store(true)
+		}
+	}))
+	defer plain.Close()
+
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, plain.URL+"/page", http.StatusFound)
+	}))
+	defer secure.Close()
+
+	netrc, _ := ParseNetrc(strings.NewReader("machine 127.0.0.1 login alice password s3cret"))
+	u, _ := url.Parse(secure.URL)
+	tests := []struct {
+		name string
+		auth *Auth
+	}{
+		{"command line", &Auth{User: "alice", Password: "s3cret", Origins: []string{Origin(u)}, NoChallenge: true}},
+		{"netrc", &Auth{Netrc: netrc, NoChallenge: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaked.Store(false)
+			client := NewClient(Options{Auth: tt.auth, TLS: secure.Client().Transport.(*http.Transport).TLSClientConfig})
+			resp, err := client.Get(context.Background(), secure.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if leaked.Load() {
+				t.Error("credentials sent over http after redirect from https")
+			}
+		})
+	}
+}
+
+// TestOrigin тест приведения адреса к origin
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		url, origin string
+	}{
+		{"https://Example.com/a", "https://example.com:443"},
+		{"https://example.com:443/", "https://example.com:443"},
+		{"http://example.com/", "http://example.com:80"},
+		{"http://example.com:8080/", "http://example.com:8080"},
+		{"http://[::1]/", "http://[::1]:80"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := Origin(u); got != tt.origin {
+			t.Errorf("Origin(%s) = %s, want %s", tt.url, got, tt.origin)
+		}
+	}
+}
+
+// TestRobotsAuth тест загрузки robots.txt с учётными данными
+func TestRobotsAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="docs"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/private/page.html")
+	client := NewClient(Options{Auth: &Auth{User: "alice", Password: "s3cret", Origins: []string{Origin(u)}}})
+	if NewRobotsCache(client, client.UserAgent()).Allowed(context.Background(), u) {
+		t.Error("expected robots.txt behind authentication to disallow /private/")
+	}
+}
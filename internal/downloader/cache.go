@@ -0,0 +1,658 @@
+package downloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize размер кеша ответов по умолчанию
+const DefaultCacheSize = 1 << 30
+
+// Cache хранит ответы на диске по правилам RFC 9111. Каталог кеша общий для повторных
+// запусков и соседних заданий, поэтому кеш ведёт себя как разделяемый: ответы с private
+// и с Set-Cookie не сохраняются, а ответы на запросы с учётными данными - только с public.
+// Варианты ответа с Vary хранятся отдельно. Тела хранятся раскодированными
+type Cache struct {
+	dir      string
+	maxSize  int64 // 0 - без ограничения
+	readOnly bool  // ответы отдаются, но ничего не сохраняется и не обновляется
+	mu       sync.Mutex
+	size     int64 // занятое место; -1 - ещё не подсчитано
+}
+
+// OpenCache открывает кеш в каталоге dir, создавая его; maxSize <= 0 - без ограничения размера
+func OpenCache(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("open cache: %w", err)
+	}
+	return &Cache{dir: dir, maxSize: max(0, maxSize), size: -1}, nil
+}
+
+// OpenCacheReadOnly открывает кеш в каталоге dir только для чтения (--spider): сохранённые
+// ответы отдаются, но в каталог ничего не пишется, и сам каталог не создаётся
+func OpenCacheReadOnly(dir string) *Cache {
+	return &Cache{dir: dir, readOnly: true, size: -1}
+}
+
+// CacheMissError документа нет в кеше, а запросы в сеть запрещены (--cache-only)
+type CacheMissError struct {
+	URL string
+}
+
+// Error реализует error
+func (e *CacheMissError) Error() string {
+	return fmt.Sprintf("%s is not in the cache", e.URL)
+}
+
+// cacheEntry метаданные сохранённого ответа; тело лежит рядом в файле Body.
+// Для ответа с Vary по адресу лежит указатель с Variants, а варианты - под ключами
+// из адреса и значений перечисленных заголовков запроса
+type cacheEntry struct {
+	URL          string            `json:"url"`
+	Status       int               `json:"status,omitempty"`
+	Header       http.Header       `json:"header,omitempty"`
+	Vary         map[string]string `json:"vary,omitempty"`     // значения заголовков запроса варианта
+	Variants     []string          `json:"variants,omitempty"` // у указателя: заголовки, выбирающие вариант
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+	Body         string            `json:"body,omitempty"`
+}
+
+// cacheKey имя файлов записи для URL
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// key имя файлов записи: из URL, а у варианта ответа с Vary - и из значений заголовков запроса
+func (e *cacheEntry) key() string {
+	id := e.URL
+	for _, name := range slices.Sorted(maps.Keys(e.Vary)) {
+		id += "\n" + name + ": " + e.Vary[name]
+	}
+	return cacheKey(id)
+}
+
+// varyValues значения заголовков names в header. Accept-Encoding не в счёт: тела
+// хранятся раскодированными; nil, если других заголовков нет
+func varyValues(names []string, header http.Header) map[string]string {
+	var values map[string]string
+	for _, name := range names {
+		if name == "Accept-Encoding" {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[name] = strings.Join(header.Values(name), ", ")
+	}
+	return values
+}
+
+// path путь файла name в кеше; файлы раскладываются по подкаталогам из первых символов ключа
+func (c *Cache) path(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// lookup находит сохранённый ответ для url и запроса с заголовками header и открывает
+// его тело; nil, если подходящего ответа нет
+func (c *Cache) lookup(url string, header http.Header) (*cacheEntry, *os.File) {
+	entry := c.readMeta(cacheKey(url), url)
+	if entry != nil && len(entry.Variants) > 0 {
+		// ответ с Vary: вариант выбирается по заголовкам, с которыми уйдёт запрос
+		c.touch(cacheKey(url))
+		vary := varyValues(entry.Variants, header)
+		entry = c.readMeta((&cacheEntry{URL: url, Vary: vary}).key(), url)
+		if entry != nil && !maps.Equal(entry.Vary, vary) {
+			entry = nil
+		}
+	}
+	// сжатое тело отдать нельзя: ответы из кеша не раскодируются
+	if entry == nil || entry.Body == "" || entry.Header.Get("Content-Encoding") != "" {
+		return nil, nil
+	}
+	body, err := os.Open(c.path(entry.Body))
+	if err != nil {
+		return nil, nil
+	}
+	c.touch(entry.key())
+	return entry, body
+}
+
+// readMeta читает метаданные записи key для url; nil, если их нет
+func (c *Cache) readMeta(key, url string) *cacheEntry {
+	data, err := os.ReadFile(c.path(key + ".json"))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	return &entry
+}
+
+// touch отмечает использование записи key: время изменения метаданных - время
+// последнего использования для вытеснения
+func (c *Cache) touch(key string) {
+	if !c.readOnly {
+		now := time.Now()
+		os.Chtimes(c.path(key+".json"), now, now)
+	}
+}
+
+// saveMeta атомарно записывает метаданные записи; тело прежней записи с тем же ключом
+// удаляется, уже открытые его читатели дочитают файл
+func (c *Cache) saveMeta(entry *cacheEntry) error {
+	if c.readOnly {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	meta := c.path(entry.key() + ".json")
+	prev, _ := os.ReadFile(meta)
+	tmp := fmt.Sprintf("%s.%d.tmp", meta, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, meta); err != nil {
+		return err
+	}
+	var old cacheEntry
+	if json.Unmarshal(prev, &old) == nil && old.Body != "" && old.Body != entry.Body {
+		os.Remove(c.path(old.Body))
+	}
+	return nil
+}
+
+// store сохраняет запись с телом из временного файла body размера size
+func (c *Cache) store(entry *cacheEntry, body string, size int64) error {
+	entry.Body = fmt.Sprintf("%s-%d.body", entry.key(), time.Now().UnixNano())
+	if err := os.Rename(body, c.path(entry.Body)); err != nil {
+		return err
+	}
+	if err := c.saveMeta(entry); err != nil {
+		os.Remove(c.path(entry.Body))
+		return err
+	}
+	if len(entry.Vary) > 0 {
+		// указатель по адресу: по каким заголовкам запроса выбирается вариант
+		c.saveMeta(&cacheEntry{URL: entry.URL, Variants: slices.Sorted(maps.Keys(entry.Vary))})
+	}
+	c.grow(size)
+	return nil
+}
+
+// grow учитывает size новых байт и вытесняет давно не использованные записи, если кеш переполнен
+func (c *Cache) grow(size int64) {
+	if c.maxSize == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size >= 0 {
+		c.size += size
+		if c.size <= c.maxSize {
+			return
+		}
+	}
+	c.size = c.evict(c.maxSize - c.maxSize/10)
+}
+
+// evict удаляет записи, начиная с давно не использованных, пока кеш не станет не больше limit,
+// и возвращает итоговый размер. Другие задания могли изменить кеш, поэтому размер считается заново
+func (c *Cache) evict(limit int64) int64 {
+	type group struct {
+		files []string
+		size  int64
+		used  time.Time
+	}
+	groups := make(map[string]*group)
+	var total int64
+	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		// файлы одной записи начинаются с её ключа
+		key := d.Name()[:min(len(d.Name()), sha256.Size*2)]
+		g := groups[key]
+		if g == nil {
+			g = &group{}
+			groups[key] = g
+		}
+		g.files = append(g.files, path)
+		g.size += info.Size()
+		if info.ModTime().After(g.used) {
+			g.used = info.ModTime()
+		}
+		total += info.Size()
+		return nil
+	})
+	if total <= limit {
+		return total
+	}
+
+	order := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		order = append(order, g)
+	}
+	slices.SortFunc(order, func(a, b *group) int { return a.used.Compare(b.used) })
+	for _, g := range order {
+		if total <= limit {
+			break
+		}
+		for _, file := range g.files {
+			os.Remove(file)
+		}
+		total -= g.size
+	}
+	return total
+}
+
+// CacheMode как задание использует кеш
+type CacheMode int
+
+// Режимы кеша
+const (
+	CacheOnline  CacheMode = iota // отвечать из кеша, а остальное запрашивать по сети
+	CacheOffline                  // отвечать только из кеша, без сети (--cache-only)
+)
+
+// CacheOptions как задание использует кеш
+type CacheOptions struct {
+	Mode CacheMode
+	// Header заголовки, с которыми запрос уйдёт в сеть (Client.Header): по ним выбирается
+	// вариант ответа с Vary. nil - только заголовки Request
+	Header func(ctx context.Context, req *Request) http.Header
+	// AuthCookies cookies задания - учётные данные (вход через форму, --load-cookies):
+	// ответы на запросы с ними, как и с Authorization, сохраняются только с public
+	AuthCookies bool
+}
+
+// Middleware отвечает на запросы из кеша, пока ответы свежие, перепроверяет устаревшие
+// по валидаторам и сохраняет новые ответы. Для nil кеша запросы проходят мимо
+func (c *Cache) Middleware(opts CacheOptions) Middleware {
+	return func(next Fetcher) Fetcher {
+		if c == nil {
+			return next
+		}
+		return &cacheFetcher{next: next, cache: c, opts: opts}
+	}
+}
+
+// cacheFetcher Fetcher поверх кеша
+type cacheFetcher struct {
+	next  Fetcher
+	cache *Cache
+	opts  CacheOptions
+}
+
+// header заголовки, с которыми req уйдёт в сеть
+func (f *cacheFetcher) header(ctx context.Context, req *Request) http.Header {
+	if f.opts.Header == nil {
+		return req.Header
+	}
+	return f.opts.Header(ctx, req)
+}
+
+// Fetch реализует Fetcher
+func (f *cacheFetcher) Fetch(ctx context.Context, req *Request) (*Response, error) {
+	if f.opts.Mode == CacheOffline {
+		// без сети диапазон не продолжить, поэтому отдаётся документ целиком
+		var entry *cacheEntry
+		var body *os.File
+		if req.Form == nil {
+			entry, body = f.cache.lookup(req.URL, f.header(ctx, req))
+		}
+		if entry == nil {
+			return nil, &CacheMissError{URL: req.URL}
+		}
+		return entry.response(req, body, time.Now()), nil
+	}
+
+	directives := parseCacheControl(req.Header.Values("Cache-Control"))
+	if req.Form != nil || req.Offset > 0 || directives.has("no-store") {
+		return f.next.Fetch(ctx, req)
+	}
+	noCache := directives.has("no-cache") || req.Header.Get("Pragma") == "no-cache"
+
+	entry, body := f.cache.lookup(req.URL, f.header(ctx, req))
+	if entry != nil && !noCache && entry.fresh(time.Now()) {
+		return entry.response(req, body, time.Now()), nil
+	}
+
+	// устаревший ответ перепроверяется по его валидаторам; валидаторы запроса (-N)
+	// сравниваются потом с сохранённым ответом
+	outgoing := req
+	validators := entry.validators()
+	if validators != (Validators{}) {
+		conditional := *req
+		conditional.Validators = validators
+		outgoing = &conditional
+	}
+
+	requestTime := time.Now()
+	resp, err := f.next.Fetch(ctx, outgoing)
+	if err != nil {
+		if body != nil {
+			body.Close()
+		}
+		return nil, err
+	}
+	if outgoing != req && resp.NotModified {
+		resp.Body.Close()
+		entry.update(resp.Header, requestTime, time.Now())
+		f.cache.saveMeta(entry)
+		return entry.response(req, body, time.Now()), nil
+	}
+	if body != nil {
+		body.Close()
+	}
+	if storable(resp, f.opts.AuthCookies) {
+		f.cache.fill(req.URL, resp, requestTime)
+	}
+	return resp, nil
+}
+
+// storable можно ли сохранить ответ в разделяемом кеше (RFC 9111, 3). Ответы после редиректов
+// не сохраняются: из кеша они вернулись бы без проверки адресов цепочки. authCookies - cookies
+// запроса считаются учётными данными
+func storable(resp *Response, authCookies bool) bool {
+	if resp.Status != http.StatusOK || resp.Offset > 0 || len(resp.Redirects) > 0 || resp.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	directives := parseCacheControl(resp.Header.Values("Cache-Control"))
+	if directives.has("no-store") || directives.has("private") {
+		return false
+	}
+	// ответ на запрос с учётными данными личный, если сервер явно не разрешил его хранить,
+	// а кеш видят другие задания
+	auth := resp.RequestHeader.Get("Authorization") != "" || authCookies && resp.RequestHeader.Get("Cookie") != ""
+	if auth && !directives.has("public") && !directives.has("s-maxage") && !directives.has("must-revalidate") {
+		return false
+	}
+	if slices.Contains(varyNames(resp.Header), "*") {
+		return false
+	}
+	// ответ без срока свежести и без валидаторов сохранять бесполезно
+	entry := cacheEntry{Status: resp.Status, Header: resp.Header, ResponseTime: time.Now()}
+	return entry.lifetime() > 0 || entry.validators() != (Validators{})
+}
+
+// fill подменяет тело resp: прочитанные байты сохраняются в кеш, когда тело дочитано до конца
+func (c *Cache) fill(url string, resp *Response, requestTime time.Time) {
+	if c.readOnly {
+		return
+	}
+	entry := &cacheEntry{
+		URL:          url,
+		Status:       resp.Status,
+		Header:       resp.Header.Clone(),
+		Vary:         varyValues(varyNames(resp.Header), resp.RequestHeader),
+		RequestTime:  requestTime,
+		ResponseTime: time.Now(),
+	}
+	length := resp.ContentLength
+	if resp.ContentEncoding != "" {
+		// тело сохраняется раскодированным
+		entry.Header.Del("Content-Encoding")
+		entry.Header.Del("Content-Length")
+		length = -1
+	}
+
+	// указатель варианта лежит в каталоге адреса, а вариант - в своём
+	dir := filepath.Dir(c.path(entry.key()))
+	if err := os.MkdirAll(filepath.Dir(c.path(cacheKey(url))), 0o755); err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(dir, "fill-*.tmp")
+	if err != nil {
+		return
+	}
+	resp.Body = &cacheFill{body: resp.Body, tmp: tmp, cache: c, entry: entry, length: length}
+}
+
+// cacheFill тело ответа, которое при чтении копируется во временный файл кеша
+type cacheFill struct {
+	body    io.ReadCloser
+	tmp     *os.File // nil после сохранения или отказа от него
+	cache   *Cache
+	entry   *cacheEntry
+	length  int64 // ожидаемая длина, -1 - неизвестна
+	written int64
+}
+
+// Read реализует io.Reader
+func (f *cacheFill) Read(p []byte) (int, error) {
+	n, err := f.body.Read(p)
+	if f.tmp != nil && n > 0 {
+		if _, werr := f.tmp.Write(p[:n]); werr != nil {
+			f.abandon()
+		}
+		f.written += int64(n)
+	}
+	if f.tmp != nil && err == io.EOF {
+		f.commit()
+	}
+	return n, err
+}
+
+// Close реализует io.Closer; недочитанное тело в кеш не попадает
+func (f *cacheFill) Close() error {
+	f.abandon()
+	return f.body.Close()
+}
+
+// commit сохраняет полностью прочитанное тело
+func (f *cacheFill) commit() {
+	tmp := f.tmp
+	f.tmp = nil
+	if err := tmp.Close(); err != nil || f.length >= 0 && f.written != f.length {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := f.cache.store(f.entry, tmp.Name(), f.written); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// abandon отказывается от сохранения тела
+func (f *cacheFill) abandon() {
+	if f.tmp == nil {
+		return
+	}
+	f.tmp.Close()
+	os.Remove(f.tmp.Name())
+	f.tmp = nil
+}
+
+// date время создания ответа из Date, иначе время его получения
+func (e *cacheEntry) date() time.Time {
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return date
+	}
+	return e.ResponseTime
+}
+
+// lifetime срок свежести ответа (RFC 9111, 4.2.1)
+func (e *cacheEntry) lifetime() time.Duration {
+	directives := parseCacheControl(e.Header.Values("Cache-Control"))
+	if seconds, ok := directives.seconds("s-maxage"); ok {
+		return seconds
+	}
+	if seconds, ok := directives.seconds("max-age"); ok {
+		return seconds
+	}
+	if expires := e.Header.Get("Expires"); expires != "" {
+		// некорректный Expires, например "0", означает, что ответ уже устарел
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		return t.Sub(e.date())
+	}
+	// эвристика: десятая часть времени, прошедшего с последнего изменения
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil {
+		return max(0, e.date().Sub(lastModified)/10)
+	}
+	return 0
+}
+
+// age возраст ответа в момент now (RFC 9111, 4.2.3)
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	apparent := max(0, e.ResponseTime.Sub(e.date()))
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+	corrected := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	return max(apparent, corrected) + now.Sub(e.ResponseTime)
+}
+
+// fresh можно ли отдать ответ без перепроверки
+func (e *cacheEntry) fresh(now time.Time) bool {
+	if parseCacheControl(e.Header.Values("Cache-Control")).has("no-cache") {
+		return false
+	}
+	return e.lifetime() > e.age(now)
+}
+
+// validators валидаторы сохранённого ответа; пустые, если записи нет
+func (e *cacheEntry) validators() Validators {
+	if e == nil {
+		return Validators{}
+	}
+	return Validators{ETag: e.Header.Get("ETag"), LastModified: e.Header.Get("Last-Modified")}
+}
+
+// update обновляет заголовки записи по ответу 304 на перепроверку
+func (e *cacheEntry) update(header http.Header, requestTime, responseTime time.Time) {
+	for name, values := range header {
+		if name == "Content-Length" {
+			continue
+		}
+		e.Header[name] = values
+	}
+	e.RequestTime = requestTime
+	e.ResponseTime = responseTime
+}
+
+// response ответ на req из записи. Для условного запроса (-N) документ сравнивается
+// с его валидаторами и при совпадении возвращается ответ с NotModified
+func (e *cacheEntry) response(req *Request, body *os.File, now time.Time) *Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(e.age(now)/time.Second), 10))
+	resp := &Response{
+		Status:        e.Status,
+		ContentType:   header.Get("Content-Type"),
+		ContentLength: -1,
+		Header:        header,
+		URL:           req.URL,
+	}
+	if e.notModified(req.Validators) {
+		body.Close()
+		resp.Status = http.StatusNotModified
+		resp.Body = http.NoBody
+		resp.NotModified = true
+		return resp
+	}
+	resp.Body = body
+	if info, err := body.Stat(); err == nil {
+		resp.ContentLength = info.Size()
+	}
+	return resp
+}
+
+// notModified совпадают ли валидаторы условного запроса с сохранённым документом
+func (e *cacheEntry) notModified(validators Validators) bool {
+	if validators.ETag != "" {
+		etag := strings.TrimPrefix(e.Header.Get("ETag"), "W/")
+		for _, candidate := range strings.Split(validators.ETag, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || etag != "" && strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(validators.LastModified)
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(e.Header.Get("Last-Modified"))
+	return err == nil && !lastModified.After(since)
+}
+
+// varyNames заголовки запроса, перечисленные в Vary ответа
+func varyNames(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// cacheControl директивы Cache-Control: имя - значение
+type cacheControl map[string]string
+
+// parseCacheControl разбирает значения заголовка Cache-Control
+func parseCacheControl(values []string) cacheControl {
+	directives := make(cacheControl)
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// has есть ли директива name
+func (c cacheControl) has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+// seconds значение директивы name в секундах
+func (c cacheControl) seconds(name string) (time.Duration, bool) {
+	value, ok := c[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		// некорректное значение считается нулевым сроком
+		return 0, true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
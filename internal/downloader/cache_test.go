@@ -0,0 +1,266 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// _get скачивает url через fetcher и возвращает тело
+func _get(t *testing.T, fetcher Fetcher, url string) (string, error) {
+	resp, err := fetcher.Fetch(context.Background(), &Request{URL: url})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+// _cached client с кешем cache; вариант ответа с Vary выбирается по заголовкам client
+func _cached(client *Client, cache *Cache, opts CacheOptions) Fetcher {
+	opts.Header = client.Header
+	return Wrap(client, cache.Middleware(opts))
+}
+
+// TestCache тест ответов из кеша по Cache-Control, Expires, Vary, cookies и валидаторам
+func TestCache(t *testing.T) {
+	var hits, revalidated atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=3600")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=3600")
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=3600")
+		case "/expired":
+			w.Header().Set("Expires", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				revalidated.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/personal", "/session", "/spider":
+			w.Header().Set("Cache-Control", "max-age=3600")
+		case "/public":
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=3600")
+			w.Header().Set("Vary", "User-Agent")
+		case "/vary-cookie":
+			w.Header().Set("Cache-Control", "max-age=3600")
+			w.Header().Set("Vary", "Cookie")
+			if cookie, err := r.Cookie("lang"); err == nil {
+				w.Write([]byte(cookie.Value + " "))
+			}
+		}
+		w.Write([]byte("body of " + r.URL.Path + " for " + r.UserAgent()))
+	}))
+	defer srv.Close()
+
+	cache, err := OpenCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path        string
+		hits        int32 // запросов к серверу за две загрузки
+		revalidated int32
+	}{
+		{"/fresh", 1, 0},
+		{"/no-store", 2, 0},
+		{"/private", 2, 0},
+		{"/expired", 2, 0},
+		{"/etag", 2, 1},
+	}
+	client := _cached(NewClient(Options{UserAgent: "a"}), cache, CacheOptions{})
+	for _, test := range tests {
+		hits.Store(0)
+		revalidated.Store(0)
+		first, err1 := _get(t, client, srv.URL+test.path)
+		second, err2 := _get(t, client, srv.URL+test.path)
+		if err1 != nil || err2 != nil || first != second || !strings.HasPrefix(first, "body of") {
+			t.Errorf("%s: unexpected bodies %q and %q, %v %v", test.path, first, second, err1, err2)
+		}
+		if hits.Load() != test.hits || revalidated.Load() != test.revalidated {
+			t.Errorf("%s: expected %d requests and %d revalidations, got %d and %d",
+				test.path, test.hits, test.revalidated, hits.Load(), revalidated.Load())
+		}
+	}
+
+	// ответ с Vary хранится отдельным вариантом для каждого значения заголовка
+	hits.Store(0)
+	other := _cached(NewClient(Options{UserAgent: "b"}), cache, CacheOptions{})
+	for _, fetcher := range []Fetcher{client, other, client, other} {
+		_get(t, fetcher, srv.URL+"/vary")
+	}
+	if body, _ := _get(t, other, srv.URL+"/vary"); body != "body of /vary for b" || hits.Load() != 2 {
+		t.Errorf("expected one cached variant per agent, got %q after %d requests", body, hits.Load())
+	}
+
+	// вариант по Vary: Cookie выбирается по cookies, которые клиент отправит
+	hits.Store(0)
+	jar, _ := cookiejar.New(nil)
+	u, _ := url.Parse(srv.URL)
+	withCookies := _cached(NewClient(Options{UserAgent: "a", Jar: jar}), cache, CacheOptions{})
+	jar.SetCookies(u, []*http.Cookie{{Name: "lang", Value: "en"}})
+	_get(t, withCookies, srv.URL+"/vary-cookie")
+	_get(t, client, srv.URL+"/vary-cookie")
+	jar.SetCookies(u, []*http.Cookie{{Name: "lang", Value: "de"}})
+	_get(t, withCookies, srv.URL+"/vary-cookie")
+	jar.SetCookies(u, []*http.Cookie{{Name: "lang", Value: "en"}})
+	if body, _ := _get(t, withCookies, srv.URL+"/vary-cookie"); body != "en body of /vary-cookie for a" || hits.Load() != 3 {
+		t.Errorf("expected cached variant for the cookie, got %q after %d requests", body, hits.Load())
+	}
+
+	// cookies сайта не мешают кешированию, а ответы на запросы с cookies-учётными
+	// данными (вход, cookies.txt) сохраняются только с public
+	authCookies := _cached(NewClient(Options{UserAgent: "a", Jar: jar}), cache, CacheOptions{AuthCookies: true})
+	for _, test := range []struct {
+		fetcher Fetcher
+		path    string
+		hits    int32
+	}{
+		{withCookies, "/personal", 1},
+		{authCookies, "/session", 2},
+		{authCookies, "/public", 1},
+	} {
+		hits.Store(0)
+		_get(t, test.fetcher, srv.URL+test.path)
+		if body, _ := _get(t, test.fetcher, srv.URL+test.path); hits.Load() != test.hits || body != "body of "+test.path+" for a" {
+			t.Errorf("%s: expected %d requests, got %d with %q", test.path, test.hits, hits.Load(), body)
+		}
+	}
+
+	// кеш только для чтения (--spider) отдаёт сохранённые ответы, но ничего не пишет
+	hits.Store(0)
+	emptyDir := filepath.Join(t.TempDir(), "cache")
+	for _, readOnly := range []*Cache{OpenCacheReadOnly(cache.dir), OpenCacheReadOnly(emptyDir)} {
+		spider := _cached(NewClient(Options{UserAgent: "a"}), readOnly, CacheOptions{})
+		_get(t, spider, srv.URL+"/fresh")
+		_get(t, spider, srv.URL+"/spider")
+	}
+	if hits.Load() != 3 {
+		t.Errorf("expected only /fresh from the read-only cache, got %d requests", hits.Load())
+	}
+	if _, err := os.Stat(emptyDir); !os.IsNotExist(err) {
+		t.Errorf("expected read-only cache not to create its directory, got %v", err)
+	}
+	if entry, body := cache.lookup(srv.URL+"/spider", nil); entry != nil {
+		body.Close()
+		t.Error("expected read-only cache not to store responses")
+	}
+
+	// --cache-only отдаёт и устаревшие ответы, а отсутствующие не запрашивает
+	srv.Close()
+	offline := _cached(NewClient(Options{UserAgent: "a"}), cache, CacheOptions{Mode: CacheOffline})
+	if body, err := _get(t, offline, srv.URL+"/etag"); err != nil || body != "body of /etag for a" {
+		t.Errorf("expected stale response offline, got %q, %v", body, err)
+	}
+	if body, err := _get(t, offline, srv.URL+"/vary"); err != nil || body != "body of /vary for a" {
+		t.Errorf("expected cached variant offline, got %q, %v", body, err)
+	}
+	var missErr *CacheMissError
+	if _, err := _get(t, offline, srv.URL+"/missing"); !errors.As(err, &missErr) {
+		t.Errorf("expected CacheMissError, got %v", err)
+	}
+}
+
+// TestCacheEncoded тест сжатого ответа: в кеш попадает раскодированное тело, а исходные байты
+// по-прежнему доступны через WriteEncodedTo под middleware
+func TestCacheEncoded(t *testing.T) {
+	page := []byte(strings.Repeat("<p>compressible page</p>", 100))
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Vary", "Accept-Encoding")
+		w.Write(_encode(t, "gzip", page))
+	}))
+	defer srv.Close()
+
+	cache, err := OpenCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := Wrap(NewClient(Options{}), cache.Middleware(CacheOptions{}), RateLimit(1<<20))
+	for i := 0; i < 2; i++ {
+		resp, err := fetcher.Fetch(context.Background(), &Request{URL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var raw bytes.Buffer
+		encoded := resp.WriteEncodedTo(&raw)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || !bytes.Equal(body, page) {
+			t.Fatalf("fetch %d: unexpected body (%d bytes), %v", i, len(body), err)
+		}
+		// первый ответ из сети, второй - из кеша, уже раскодированный
+		if fromNetwork := i == 0; encoded != fromNetwork || resp.ContentEncoding != "" != fromNetwork {
+			t.Errorf("fetch %d: expected encoded %v, got %v with Content-Encoding %q", i, fromNetwork, encoded, resp.ContentEncoding)
+		}
+		if i == 0 && !bytes.Equal(raw.Bytes(), _encode(t, "gzip", page)) {
+			t.Errorf("expected raw gzip bytes through the middlewares, got %d bytes", raw.Len())
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected one request to the server, got %d", hits.Load())
+	}
+}
+
+// TestCacheSize тест вытеснения давно не использованных ответов
+func TestCacheSize(t *testing.T) {
+	page := strings.Repeat("x", 4<<10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cache, err := OpenCache(dir, 10<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := _cached(NewClient(Options{}), cache, CacheOptions{})
+	for _, path := range []string{"/1", "/2", "/3", "/4"} {
+		if _, err := _get(t, client, srv.URL+path); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if size > 10<<10 {
+		t.Errorf("expected cache within 10K, got %d bytes", size)
+	}
+	if entry, body := cache.lookup(srv.URL+"/4", nil); entry == nil {
+		t.Error("expected the most recent response to stay in cache")
+	} else {
+		body.Close()
+	}
+}
@@ -2,30 +2,511 @@ package downloader
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// Get получение документа
-func Get(ctx context.Context, url string) (io.ReadCloser, string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// DefaultMaxRedirects сколько редиректов выполняется по умолчанию, как в wget
+const DefaultMaxRedirects = 20
+
+// Options настройки Client
+type Options struct {
+	UserAgent string      // пустое значение заменяется на UserAgent
+	Headers   http.Header // заголовки, добавляемые к каждому запросу
+	Timeouts  Timeouts
+	Jar       http.CookieJar // nil - cookies не хранятся
+	Auth      *Auth          // nil - без аутентификации
+	Proxy     *Proxy         // nil - прокси из переменных окружения
+	TLS       *tls.Config    // nil - настройки Go по умолчанию
+	// MaxRedirects сколько редиректов выполняется для одного запроса;
+	// 0 - DefaultMaxRedirects, меньше 0 - редиректы не выполняются
+	MaxRedirects int
+	// NoCompression не запрашивать сжатые ответы (Accept-Encoding: identity)
+	NoCompression bool
+	// MaxRatio во сколько раз раскодированное тело может быть больше полученного;
+	// 0 - DefaultMaxRatio, меньше 0 - без ограничения
+	MaxRatio int
+}
+
+// Client выполняет http запросы от имени утилиты
+type Client struct {
+	httpClient *http.Client
+	userAgent  string
+	headers    http.Header
+	timeouts   Timeouts
+	redirects  int
+	encoding   string // значение Accept-Encoding
+	maxRatio   int
+}
+
+// NewClient инициализирует Client
+func NewClient(opts Options) *Client {
+	// User-Agent из дополнительных заголовков - такая же идентичность, как -U,
+	// и по нему же проверяются правила robots.txt
+	headers := opts.Headers.Clone()
+	userAgent := opts.UserAgent
+	if userAgent == "" {
+		userAgent = headers.Get("User-Agent")
+	}
+	if userAgent == "" {
+		userAgent = UserAgent
+	}
+	headers.Del("User-Agent")
+	timeouts := opts.Timeouts.WithDefaults(0)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = timeouts.dialContext(&net.Dialer{
+		Timeout:   timeouts.Connect,
+		KeepAlive: 30 * time.Second,
+	})
+	transport.Proxy = opts.Proxy.proxyFunc()
+	if opts.TLS != nil {
+		transport.TLSClientConfig = opts.TLS
+	}
+	transport.TLSHandshakeTimeout = timeouts.TLS
+	transport.ResponseHeaderTimeout = timeouts.Response
+
+	redirects := opts.MaxRedirects
+	if redirects == 0 {
+		redirects = DefaultMaxRedirects
+	}
+	maxRatio := opts.MaxRatio
+	if maxRatio == 0 {
+		maxRatio = DefaultMaxRatio
+	}
+	// с явным Accept-Encoding http.Transport не раскодирует gzip сам, это делает response
+	encoding := AcceptEncoding
+	if opts.NoCompression {
+		encoding = "identity"
+	}
+
+	c := &Client{
+		userAgent: userAgent,
+		headers:   headers,
+		timeouts:  timeouts,
+		redirects: max(0, redirects),
+		encoding:  encoding,
+		maxRatio:  max(0, maxRatio),
+	}
+	c.httpClient = &http.Client{
+		Transport:     newAuthTransport(transport, opts.Auth),
+		Jar:           opts.Jar,
+		CheckRedirect: c.checkRedirect,
+	}
+	return c
+}
+
+// ParseHeaders разбирает заголовки вида "Name: value"
+func ParseHeaders(lines []string) (http.Header, error) {
+	headers := make(http.Header, len(lines))
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q: expected \"Name: value\"", line)
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// refererKey ключ контекста для заголовка Referer
+type refererKey struct{}
+
+// WithReferer возвращает контекст, запросы с которым отправляют заголовок Referer
+func WithReferer(ctx context.Context, referer string) context.Context {
+	if referer == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, refererKey{}, referer)
+}
+
+// redirectKey ключ контекста для проверки редиректов
+type redirectKey struct{}
+
+// WithRedirectCheck возвращает контекст, запросы с которым следуют только редиректам,
+// адрес которых одобрил allow. Отклонённый редирект возвращается как RedirectError
+func WithRedirectCheck(ctx context.Context, allow func(to *url.URL) bool) context.Context {
+	return context.WithValue(ctx, redirectKey{}, allow)
+}
+
+// checkRedirect ограничивает число редиректов и проверяет их адреса для http.Client
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > c.redirects {
+		return &RedirectError{URL: req.URL.String(), Limit: c.redirects}
+	}
+	if allow, ok := req.Context().Value(redirectKey{}).(func(*url.URL) bool); ok && !allow(req.URL) {
+		return &RedirectError{URL: req.URL.String(), Rejected: true}
+	}
+	return nil
+}
+
+// UserAgent возвращает пользовательский агент, с которым выполняются запросы
+func (c *Client) UserAgent() string {
+	return c.userAgent
+}
+
+// Response ответ на запрос документа
+type Response struct {
+	Status          int           // код ответа после редиректов
+	Body            io.ReadCloser // тело ответа читается потоком, уже раскодированным, и должно быть закрыто
+	ContentType     string
+	ContentEncoding string // кодирование, в котором тело пришло по сети (gzip, deflate, br)
+	ContentLength   int64  // длина тела, -1, если размер неизвестен
+	Offset          int64  // с какого байта документа начинается тело; больше 0 только при продолжении загрузки
+	NotModified     bool   // документ не изменился с прошлой загрузки (304), тело пустое
+	Header          http.Header
+	URL             string   // адрес, с которого получен ответ, после всех редиректов
+	Redirects       []string // адреса, с которых выполнены редиректы, по порядку; первый - запрошенный
+	// RequestHeader заголовки последнего запроса цепочки, в том числе Cookie и Authorization
+	RequestHeader http.Header
+
+	// тело, полученное по сети, и его раскодировщик; middleware оборачивают Body, а не их
+	idle    *idleReader
+	encoded *encodedBody
+}
+
+// WriteEncodedTo задаёт w, в который при чтении Body пишутся полученные по сети,
+// ещё не раскодированные байты. Вызывается до чтения Body; false, если тело пришло без кодирования
+func (r *Response) WriteEncodedTo(w io.Writer) bool {
+	if r.encoded == nil {
+		return false
+	}
+	r.encoded.encoded.w = w
+	return true
+}
+
+// Validator валидатор документа для If-Range: сильный ETag, иначе Last-Modified
+func (r *Response) Validator() string {
+	if etag := r.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return r.Header.Get("Last-Modified")
+}
+
+// Validators валидаторы документа, по которым сервер определяет, изменился ли он
+func (r *Response) Validators() Validators {
+	return Validators{ETag: r.Header.Get("ETag"), LastModified: r.Header.Get("Last-Modified")}
+}
+
+// Validators валидаторы документа из прошлой загрузки
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Get получение документа. Тело ответа нужно закрыть; если данные не приходят
+// дольше таймаута чтения, запрос прерывается
+func (c *Client) Get(ctx context.Context, url string) (*Response, error) {
+	return c.GetFrom(ctx, url, 0, "")
+}
+
+// GetIfModified получение документа, если он изменился с загрузки, описанной validators.
+// Для неизменившегося документа возвращается ответ с NotModified
+func (c *Client) GetIfModified(ctx context.Context, url string, validators Validators) (*Response, error) {
+	return c.getIfModified(ctx, url, validators, nil)
+}
+
+// getIfModified GetIfModified с дополнительными заголовками запроса
+func (c *Client) getIfModified(ctx context.Context, url string, validators Validators, extra http.Header) (*Response, error) {
+	header := extra.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	if validators.ETag != "" {
+		header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, cancel, err := c.do(ctx, http.MethodGet, url, header, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	switch {
+	case successful(resp.StatusCode):
+		return c.response(resp, 0, cancel)
+	case resp.StatusCode == http.StatusNotModified:
+		cancel()
+		resp.Body.Close()
+		final, redirects := redirectChain(resp)
+		return &Response{Status: resp.StatusCode, Body: http.NoBody, NotModified: true, Header: resp.Header, URL: final, Redirects: redirects}, nil
+	default:
+		return nil, statusError(resp, cancel)
+	}
+}
+
+// GetFrom получение документа начиная с байта offset, если документ не изменился
+// с момента, описанного validator. Если сервер не поддерживает диапазоны или документ
+// изменился, возвращается документ целиком с Offset 0. Если документ уже получен
+// целиком, возвращается пустое тело с Offset, равным offset
+func (c *Client) GetFrom(ctx context.Context, url string, offset int64, validator string) (*Response, error) {
+	return c.getFrom(ctx, url, offset, validator, nil)
+}
+
+// getFrom GetFrom с дополнительными заголовками запроса
+func (c *Client) getFrom(ctx context.Context, url string, offset int64, validator string, extra http.Header) (*Response, error) {
+	if offset <= 0 || validator == "" {
+		offset = 0
+	}
+
+	header := extra.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		header.Set("If-Range", validator)
+		// диапазон сжатого ответа относится к сжатым байтам, а продолжаются только несжатые загрузки
+		header.Set("Accept-Encoding", "identity")
+	}
+
+	resp, cancel, err := c.do(ctx, http.MethodGet, url, header, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if start, err := parseContentRange(resp.Header.Get("Content-Range")); err != nil || start != offset {
+			cancel()
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected Content-Range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+		return c.response(resp, offset, cancel)
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		cancel()
+		resp.Body.Close()
+		if size, ok := parseUnsatisfiedRange(resp.Header.Get("Content-Range")); ok && size == offset {
+			final, redirects := redirectChain(resp)
+			return &Response{
+				Status:      resp.StatusCode,
+				Body:        http.NoBody,
+				ContentType: resp.Header.Get("Content-Type"),
+				Offset:      offset,
+				Header:      resp.Header,
+				URL:         final,
+				Redirects:   redirects,
+			}, nil
+		}
+		// частично скачанный файл длиннее документа на сервере: скачиваем заново
+		return c.getFrom(ctx, url, 0, "", extra)
+	case successful(resp.StatusCode) && resp.StatusCode != http.StatusPartialContent:
+		// 206 без запроса диапазона - лишь часть документа, такой ответ не принимается
+		return c.response(resp, 0, cancel)
+	default:
+		return nil, statusError(resp, cancel)
+	}
+}
+
+// PostForm отправляет форму form на адрес action методом POST, как браузер. Редиректы
+// после отправки выполняются запросами GET; возвращается ответ в конце цепочки редиректов
+func (c *Client) PostForm(ctx context.Context, action string, form url.Values, extra http.Header) (*Response, error) {
+	header := extra.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, cancel, err := c.do(ctx, http.MethodPost, action, header, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	if !successful(resp.StatusCode) {
+		return nil, statusError(resp, cancel)
+	}
+	return c.response(resp, 0, cancel)
+}
+
+// successful код ответа из 2xx: документ получен, хотя бы пустой (204, 205)
+func successful(code int) bool {
+	return code >= 200 && code <= 299
+}
+
+// do выполняет запрос с заголовками клиента и header. Контекст запроса
+// отменяется cancel, который нужно вызвать после чтения тела
+func (c *Client) do(ctx context.Context, method, url string, header http.Header, body io.Reader) (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	req.Header = c.requestHeader(ctx, req.URL, header)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return resp, cancel, nil
+}
+
+// requestHeader заголовки запроса на u: заголовки клиента, header, User-Agent, Accept-Encoding и Referer
+func (c *Client) requestHeader(ctx context.Context, u *url.URL, header http.Header) http.Header {
+	result := make(http.Header)
+	for name, values := range c.headers {
+		for _, value := range values {
+			result.Add(name, value)
+		}
+	}
+	for name, values := range header {
+		result[name] = values
+	}
+	result.Set("User-Agent", c.userAgent)
+	if result.Get("Accept-Encoding") == "" {
+		result.Set("Accept-Encoding", c.encoding)
+	}
+	// как браузеры, не раскрываем адрес HTTPS страницы в запросе по HTTP
+	if referer, ok := ctx.Value(refererKey{}).(string); ok && !(strings.HasPrefix(referer, "https:") && u.Scheme == "http") {
+		result.Set("Referer", referer)
+	}
+	return result
+}
+
+// Header заголовки, с которыми Client отправит req, включая cookies. Authorization не входит:
+// она зависит от ответа сервера. По этим заголовкам кеш выбирает вариант ответа с Vary
+func (c *Client) Header(ctx context.Context, req *Request) http.Header {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return req.Header
+	}
+	header := c.requestHeader(ctx, u, req.Header)
+	if c.httpClient.Jar != nil {
+		// cookies добавляются так же, как их добавляет http.Client
+		r := &http.Request{Header: header}
+		for _, cookie := range c.httpClient.Jar.Cookies(u) {
+			r.AddCookie(cookie)
+		}
+	}
+	return header
+}
+
+// response оборачивает успешный ответ: тело читается с таймаутом простоя и раскодируется
+func (c *Client) response(resp *http.Response, offset int64, cancel context.CancelFunc) (*Response, error) {
+	final, redirects := redirectChain(resp)
+	idle := newIdleReader(resp.Body, c.timeouts.Read, cancel)
+	r := &Response{
+		Status:        resp.StatusCode,
+		Body:          idle,
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: resp.ContentLength,
+		Offset:        offset,
+		Header:        resp.Header,
+		URL:           final,
+		Redirects:     redirects,
+		RequestHeader: resp.Request.Header,
+		idle:          idle,
+	}
+
+	encoding := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
+	if encoding == "" || strings.EqualFold(encoding, "identity") {
+		return r, nil
+	}
+	body, err := newEncodedBody(r.Body, encoding, c.maxRatio)
 	if err != nil {
-		return nil, "", err
+		r.Body.Close()
+		return nil, err
+	}
+	r.Body = body
+	r.encoded = body
+	r.ContentEncoding = encoding
+	// длина раскодированного тела заранее неизвестна
+	r.ContentLength = -1
+	return r, nil
+}
+
+// redirectChain адрес ответа и адреса запросов, ответы на которые были редиректами
+func redirectChain(resp *http.Response) (string, []string) {
+	var redirects []string
+	for prev := resp.Request.Response; prev != nil; prev = prev.Request.Response {
+		redirects = append(redirects, prev.Request.URL.String())
+	}
+	slices.Reverse(redirects)
+	return resp.Request.URL.String(), redirects
+}
+
+// statusError закрывает неуспешный ответ и возвращает StatusError
+func statusError(resp *http.Response, cancel context.CancelFunc) error {
+	defer cancel()
+	defer resp.Body.Close()
+	return &StatusError{
+		Code:       resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		return nil, "", fmt.Errorf("status code %d", resp.StatusCode)
+// parseContentRange возвращает первый байт диапазона из "bytes 100-199/200"
+func parseContentRange(value string) (int64, error) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, fmt.Errorf("invalid Content-Range %q", value)
 	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, fmt.Errorf("invalid Content-Range %q", value)
+	}
+	return strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+}
 
-	return resp.Body, resp.Header.Get("Content-Type"), nil
+// parseUnsatisfiedRange возвращает размер документа из "bytes */200"
+func parseUnsatisfiedRange(value string) (int64, bool) {
+	spec, ok := strings.CutPrefix(value, "bytes */")
+	if !ok {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(spec), 10, 64)
+	return size, err == nil
+}
+
+// StatusError ответ сервера с кодом, отличным от 200 (206 и 304 для условных запросов)
+type StatusError struct {
+	Code       int
+	RetryAfter time.Duration // пауза из заголовка Retry-After, 0 - если заголовка нет
+}
+
+// Error реализует error
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code %d", e.Code)
+}
+
+// RedirectError редирект не выполнен: превышено число редиректов или адрес отклонён проверкой
+type RedirectError struct {
+	URL      string // адрес, на который вёл редирект
+	Rejected bool   // адрес отклонён проверкой из WithRedirectCheck
+	Limit    int    // сколько редиректов разрешено
+}
+
+// Error реализует error
+func (e *RedirectError) Error() string {
+	if e.Rejected {
+		return fmt.Sprintf("redirect to %s rejected", e.URL)
+	}
+	return fmt.Sprintf("stopped after %d redirects at %s", e.Limit, e.URL)
+}
+
+// parseRetryAfter разбирает Retry-After: число секунд или HTTP дата
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(at))
+	}
+	return 0
 }
 
 // IsHTML описывает ли contentType HTML документ
@@ -0,0 +1,298 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mirror-wget/internal/cookies"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGetStatusError тест ошибки с кодом ответа и Retry-After
+func TestGetStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(Options{}).Get(context.Background(), srv.URL)
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected *StatusError, got %v", err)
+	}
+	if statusErr.Code != http.StatusServiceUnavailable || statusErr.RetryAfter != 2*time.Minute {
+		t.Errorf("unexpected status error %+v", statusErr)
+	}
+}
+
+// TestGetSuccessful тест: любой код 2xx - успешный ответ, а не *StatusError
+func TestGetSuccessful(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/copy":
+			w.WriteHeader(http.StatusNonAuthoritativeInfo)
+			w.Write([]byte("copy"))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/copy", http.StatusNonAuthoritativeInfo, "copy"},
+		{"/empty", http.StatusNoContent, ""},
+	}
+
+	client := NewClient(Options{})
+	for _, test := range tests {
+		for _, req := range []*Request{
+			{URL: srv.URL + test.path},
+			{URL: srv.URL + test.path, Validators: Validators{ETag: `"v1"`}},
+		} {
+			resp, err := client.Fetch(context.Background(), req)
+			if err != nil {
+				t.Fatalf("%s: %v", test.path, err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.Status != test.status || resp.NotModified || string(body) != test.body {
+				t.Errorf("%s: expected status %d and body %q, got %d and %q", test.path, test.status, test.body, resp.Status, body)
+			}
+		}
+	}
+}
+
+// TestParseRetryAfter тест разбора заголовка Retry-After
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("30"); d != 30*time.Second {
+		t.Errorf("expected 30s, got %v", d)
+	}
+	if d := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expected about 1h, got %v", d)
+	}
+	for _, value := range []string{"", "soon", "-5", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)} {
+		if d := parseRetryAfter(value); d != 0 {
+			t.Errorf("expected 0 for %q, got %v", value, d)
+		}
+	}
+}
+
+// TestGetFrom тест продолжения загрузки с заданного байта
+func TestGetFrom(t *testing.T) {
+	content := "0123456789"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/no-ranges" {
+			w.Write([]byte(content))
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file.bin", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		offset    int64
+		validator string
+		expOffset int64
+		expBody   string
+	}{
+		{"resume", "/file.bin", 4, `"v1"`, 4, "456789"},
+		{"changed", "/file.bin", 4, `"v0"`, 0, content},
+		{"no validator", "/file.bin", 4, "", 0, content},
+		{"complete", "/file.bin", 10, `"v1"`, 10, ""},
+		{"longer than document", "/file.bin", 20, `"v1"`, 0, content},
+		{"ranges unsupported", "/no-ranges", 4, `"v1"`, 0, content},
+	}
+
+	client := NewClient(Options{})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := client.GetFrom(context.Background(), srv.URL+test.path, test.offset, test.validator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Offset != test.expOffset || string(body) != test.expBody {
+				t.Errorf("expected offset %d and body %q, got %d and %q", test.expOffset, test.expBody, resp.Offset, body)
+			}
+		})
+	}
+}
+
+// TestGetIfModified тест условного запроса по ETag и Last-Modified
+func TestGetIfModified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "index.html", modified, strings.NewReader("<html></html>"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		validators  Validators
+		notModified bool
+	}{
+		{"no validators", Validators{}, false},
+		{"same etag", Validators{ETag: `"v1"`}, true},
+		{"changed etag", Validators{ETag: `"v0"`}, false},
+		{"same last modified", Validators{LastModified: modified.Format(http.TimeFormat)}, true},
+		{"older last modified", Validators{LastModified: modified.Add(-time.Hour).Format(http.TimeFormat)}, false},
+	}
+
+	client := NewClient(Options{})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := client.GetIfModified(context.Background(), srv.URL, test.validators)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.NotModified != test.notModified {
+				t.Errorf("expected not modified %v, got %v", test.notModified, resp.NotModified)
+			}
+			if v := resp.Validators(); !resp.NotModified && (v.ETag != `"v1"` || v.LastModified != modified.Format(http.TimeFormat)) {
+				t.Errorf("unexpected validators %+v", v)
+			}
+		})
+	}
+}
+
+// TestGetCookies тест cookie сессии, выставленной первым ответом и отправленной в следующих запросах
+func TestGetCookies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "42", Path: "/"})
+			return
+		}
+		if c, err := r.Cookie("session"); err != nil || c.Value != "42" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	jar := cookies.NewJar()
+	client := NewClient(Options{Jar: jar})
+	for _, path := range []string{"/", "/private/"} {
+		resp, err := client.Get(context.Background(), srv.URL+path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	if jar.Len() != 1 {
+		t.Errorf("expected 1 cookie in jar, got %d", jar.Len())
+	}
+}
+
+// TestGetIdentity тест User-Agent, дополнительных заголовков и Referer в запросах
+func TestGetIdentity(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		opts       Options
+		referer    string
+		expAgent   string
+		expReferer string
+	}{
+		{"default agent", Options{}, "", UserAgent, ""},
+		{"configured agent", Options{UserAgent: "docs-bot/1.0"}, "http://example.com/", "docs-bot/1.0", "http://example.com/"},
+		{"agent from headers", Options{Headers: http.Header{"User-Agent": {"waf-friendly/2"}}}, "", "waf-friendly/2", ""},
+		{"https referer to http", Options{}, "https://example.com/", UserAgent, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.Headers = test.opts.Headers.Clone()
+			if test.opts.Headers == nil {
+				test.opts.Headers = make(http.Header)
+			}
+			test.opts.Headers.Set("Accept-Language", "ru")
+
+			client := NewClient(test.opts)
+			resp, err := client.Get(WithReferer(context.Background(), test.referer), srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if client.UserAgent() != test.expAgent || got.Get("User-Agent") != test.expAgent {
+				t.Errorf("expected agent %q, got %q (client %q)", test.expAgent, got.Get("User-Agent"), client.UserAgent())
+			}
+			if got.Get("Referer") != test.expReferer || got.Get("Accept-Language") != "ru" {
+				t.Errorf("unexpected headers %v", got)
+			}
+		})
+	}
+}
+
+// TestGetRedirects тест цепочки редиректов, их лимита и проверки адреса
+func TestGetRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			http.Redirect(w, r, "/docs/", http.StatusMovedPermanently)
+		case "/docs/":
+			http.Redirect(w, r, "/docs/v2/", http.StatusFound)
+		case "/docs/v2/":
+			w.Write([]byte("v2"))
+		case "/away":
+			http.Redirect(w, r, "http://other.example.test/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := NewClient(Options{}).Get(context.Background(), srv.URL+"/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.URL != srv.URL+"/docs/v2/" || !reflect.DeepEqual(resp.Redirects, []string{srv.URL + "/docs", srv.URL + "/docs/"}) {
+		t.Errorf("unexpected final URL %q and redirects %v", resp.URL, resp.Redirects)
+	}
+
+	tests := []struct {
+		name     string
+		max      int
+		path     string
+		rejected bool
+	}{
+		{"limit", 1, "/docs", false},
+		{"disabled", -1, "/docs", false},
+		{"rejected", 0, "/away", true},
+	}
+	for _, test := range tests {
+		ctx := WithRedirectCheck(context.Background(), func(to *url.URL) bool {
+			return to.Host == strings.TrimPrefix(srv.URL, "http://")
+		})
+		_, err := NewClient(Options{MaxRedirects: test.max}).Get(ctx, srv.URL+test.path)
+
+		var redirectErr *RedirectError
+		if !errors.As(err, &redirectErr) || redirectErr.Rejected != test.rejected {
+			t.Errorf("%s: expected redirect error (rejected %v), got %v", test.name, test.rejected, err)
+		}
+	}
+}
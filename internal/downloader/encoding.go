@@ -0,0 +1,182 @@
+package downloader
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+	"strings"
+)
+
+// AcceptEncoding кодирования, которые клиент запрашивает и раскодирует сам
+const AcceptEncoding = "gzip, deflate, br"
+
+// DefaultMaxRatio во сколько раз раскодированное тело может превышать полученное по сети
+const DefaultMaxRatio = 100
+
+// ratioSlack сколько байт раскодированного тела не проверяется на MaxRatio:
+// маленькие однообразные документы сжимаются сильнее любого разумного предела
+const ratioSlack = 1 << 20
+
+// NewDecoder раскодирует r, закодированный по Content-Encoding encoding.
+// Несколько кодирований через запятую снимаются в обратном порядке
+func NewDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	codings := strings.Split(encoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		switch coding := strings.ToLower(strings.TrimSpace(codings[i])); coding {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(r)
+		case "deflate":
+			r, err = newDeflateReader(r)
+		case "br":
+			r = brotli.NewReader(r)
+		default:
+			return nil, fmt.Errorf("unsupported Content-Encoding %q", coding)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", strings.TrimSpace(codings[i]), err)
+		}
+	}
+	return r, nil
+}
+
+// newDeflateReader читает deflate: по RFC 9110 это zlib, но часть серверов
+// отправляет поток deflate без заголовка zlib, поэтому формат определяется по первым байтам
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// RatioError раскодированное тело во много раз больше полученного: сжатая бомба или испорченный ответ
+type RatioError struct {
+	Ratio int
+}
+
+// Error реализует error
+func (e *RatioError) Error() string {
+	return fmt.Sprintf("decoded body is more than %d times larger than received", e.Ratio)
+}
+
+// countingReader считает прочитанные байты
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read реализует io.Reader
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ratioReader прерывает чтение раскодированного тела, если оно больше ratio * полученных байт
+type ratioReader struct {
+	r        io.Reader
+	received *countingReader
+	decoded  int64
+	ratio    int
+}
+
+// Read реализует io.Reader
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.decoded += int64(n)
+	if r.decoded > ratioSlack && r.decoded > int64(r.ratio)*r.received.n {
+		return n, &RatioError{Ratio: r.ratio}
+	}
+	return n, err
+}
+
+// encodedBody тело ответа, которое раскодируется при чтении
+type encodedBody struct {
+	io.Reader
+	raw     io.ReadCloser
+	encoded *encodedTee
+}
+
+// encodedTee передаёт полученные по сети байты в w, если он задан. Заголовок
+// сжатого потока раскодировщик читает ещё до того, как задан w, поэтому эти байты копятся в pending
+type encodedTee struct {
+	w         io.Writer
+	pending   []byte
+	buffering bool
+}
+
+// Write реализует io.Writer
+func (t *encodedTee) Write(p []byte) (int, error) {
+	if t.w == nil {
+		if t.buffering {
+			t.pending = append(t.pending, p...)
+		}
+		return len(p), nil
+	}
+	if err := t.flush(); err != nil {
+		return 0, err
+	}
+	return t.w.Write(p)
+}
+
+// flush передаёт в w байты, прочитанные до того, как он был задан
+func (t *encodedTee) flush() error {
+	if t.w == nil || t.pending == nil {
+		return nil
+	}
+	pending := t.pending
+	t.pending = nil
+	_, err := t.w.Write(pending)
+	return err
+}
+
+// Read реализует io.Reader. Байты после конца сжатого потока раскодировщик не читает,
+// поэтому, если исходные байты сохраняются, они дочитываются в encoded
+func (b *encodedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF && b.encoded.w != nil {
+		if err := b.encoded.flush(); err != nil {
+			return n, err
+		}
+		if _, err := io.Copy(b.encoded, b.raw); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// Close закрывает исходное тело
+func (b *encodedBody) Close() error {
+	return b.raw.Close()
+}
+
+// newEncodedBody раскодирует raw по encoding; maxRatio 0 - без ограничения
+func newEncodedBody(raw io.ReadCloser, encoding string, maxRatio int) (*encodedBody, error) {
+	body := &encodedBody{raw: raw, encoded: &encodedTee{buffering: true}}
+	received := &countingReader{r: io.TeeReader(raw, body.encoded)}
+	decoded, err := NewDecoder(encoding, received)
+	body.encoded.buffering = false
+	if errors.Is(err, io.EOF) {
+		// пустое тело с Content-Encoding, например у ответа 204
+		decoded, err = strings.NewReader(""), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if maxRatio > 0 {
+		decoded = &ratioReader{r: decoded, received: received, ratio: maxRatio}
+	}
+	body.Reader = decoded
+	return body, nil
+}
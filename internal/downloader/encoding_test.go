@@ -0,0 +1,126 @@
+package downloader
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"github.com/andybalholm/brotli"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// _encode кодирует data по encoding
+func _encode(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		return data
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+// TestGetEncodings тест раскодирования gzip, deflate и br
+func TestGetEncodings(t *testing.T) {
+	page := []byte(strings.Repeat("<p>compressible page</p>", 100))
+	var acceptEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		encoding := r.URL.Query().Get("e")
+		header := encoding
+		if encoding == "raw-deflate" {
+			header = "deflate"
+		}
+		if header != "" {
+			w.Header().Set("Content-Encoding", header)
+		}
+		w.Write(_encode(t, encoding, page))
+	}))
+	defer srv.Close()
+
+	client := NewClient(Options{})
+	for _, encoding := range []string{"", "gzip", "deflate", "raw-deflate", "br"} {
+		t.Run(encoding, func(t *testing.T) {
+			resp, err := client.Get(context.Background(), srv.URL+"/?e="+encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var raw bytes.Buffer
+			encoded := resp.WriteEncodedTo(&raw)
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if err != nil || !bytes.Equal(body, page) {
+				t.Errorf("unexpected decoded body (%d bytes), %v", len(body), err)
+			}
+			if acceptEncoding != AcceptEncoding {
+				t.Errorf("unexpected Accept-Encoding %q", acceptEncoding)
+			}
+			if encoded != (encoding != "") {
+				t.Errorf("expected encoded %v, got %v", encoding != "", encoded)
+			}
+			if encoded && !bytes.Equal(raw.Bytes(), _encode(t, encoding, page)) {
+				t.Errorf("expected original encoded bytes, got %d bytes", raw.Len())
+			}
+		})
+	}
+
+	resp, err := NewClient(Options{NoCompression: true}).Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if acceptEncoding != "identity" {
+		t.Errorf("expected identity without compression, got %q", acceptEncoding)
+	}
+}
+
+// TestGetRatio тест прерывания ответа, раскодирование которого слишком велико
+func TestGetRatio(t *testing.T) {
+	bomb := _encode(t, "gzip", make([]byte, 8<<20))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(bomb)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		maxRatio int
+		ok       bool
+	}{
+		{"default limit", 0, false},
+		{"no limit", -1, true},
+	}
+	for _, test := range tests {
+		resp, err := NewClient(Options{MaxRatio: test.maxRatio}).Get(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		var ratioErr *RatioError
+		if test.ok && (err != nil || n != 8<<20) || !test.ok && !errors.As(err, &ratioErr) {
+			t.Errorf("%s: expected ok %v, got %d bytes, %v", test.name, test.ok, n, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Fetcher получает документы. Client получает их по HTTP; обёртки - повторы, кеш,
+// ограничение скорости - реализуют Fetcher поверх другого Fetcher
+type Fetcher interface {
+	// Fetch выполняет запрос. Тело ответа нужно закрыть; код ответа не из 2xx и не 304
+	// возвращается как *StatusError
+	Fetch(ctx context.Context, req *Request) (*Response, error)
+}
+
+// Request запрос документа
+type Request struct {
+	URL string
+	// Validators делают запрос условным (-N): для неизменившегося документа
+	// возвращается ответ с NotModified
+	Validators Validators
+	// Offset продолжение загрузки с байта Offset, если документ не изменился
+	// с момента, описанного Validator (If-Range)
+	Offset    int64
+	Validator string
+	// Header дополнительные заголовки запроса
+	Header http.Header
+	// Form если задана, отправляется методом POST (вход через форму)
+	Form url.Values
+}
+
+// FetcherFunc функция, реализующая Fetcher
+type FetcherFunc func(ctx context.Context, req *Request) (*Response, error)
+
+// Fetch реализует Fetcher
+func (f FetcherFunc) Fetch(ctx context.Context, req *Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+// Middleware оборачивает Fetcher дополнительной логикой
+type Middleware func(next Fetcher) Fetcher
+
+// Wrap оборачивает f в middlewares: первая оказывается снаружи и получает запрос первой
+func Wrap(f Fetcher, middlewares ...Middleware) Fetcher {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		f = middlewares[i](f)
+	}
+	return f
+}
+
+// Fetch реализует Fetcher по HTTP
+func (c *Client) Fetch(ctx context.Context, req *Request) (*Response, error) {
+	switch {
+	case req.Form != nil:
+		return c.PostForm(ctx, req.URL, req.Form, req.Header)
+	case req.Validators != (Validators{}):
+		return c.getIfModified(ctx, req.URL, req.Validators, req.Header)
+	default:
+		return c.getFrom(ctx, req.URL, req.Offset, req.Validator, req.Header)
+	}
+}
@@ -0,0 +1,141 @@
+package downloader
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetrcMachine учётные данные одной записи .netrc
+type NetrcMachine struct {
+	Login    string
+	Password string
+}
+
+// Netrc учётные данные из файла .netrc по хостам
+type Netrc struct {
+	machines map[string]NetrcMachine
+	fallback *NetrcMachine // запись default
+}
+
+// NetrcPath путь к .netrc: $NETRC или ~/.netrc
+func NetrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// LoadNetrc читает .netrc; если файла нет, возвращает nil без ошибки
+func LoadNetrc(path string) (*Netrc, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseNetrc(f)
+}
+
+// ParseNetrc разбирает .netrc: записи machine и default с login и password.
+// Макросы macdef пропускаются до пустой строки
+func ParseNetrc(r io.Reader) (*Netrc, error) {
+	n := &Netrc{machines: make(map[string]NetrcMachine)}
+
+	var current *NetrcMachine
+	var host string
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if host == "" {
+			if n.fallback == nil {
+				n.fallback = current
+			}
+		} else if _, ok := n.machines[host]; !ok {
+			// как в curl и wget, действует первая запись для хоста
+			n.machines[host] = *current
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			value := func() string {
+				if i+1 < len(fields) {
+					i++
+					return fields[i]
+				}
+				return ""
+			}
+
+			switch fields[i] {
+			case "machine":
+				flush()
+				current, host = &NetrcMachine{}, strings.ToLower(value())
+			case "default":
+				flush()
+				current, host = &NetrcMachine{}, ""
+			case "login":
+				if current != nil {
+					current.Login = value()
+				}
+			case "password":
+				if current != nil {
+					current.Password = value()
+				}
+			case "account":
+				value()
+			case "macdef":
+				value()
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+	flush()
+	return n, scanner.Err()
+}
+
+// Machine учётные данные из записи machine для хоста, без записи default
+func (n *Netrc) Machine(host string) (NetrcMachine, bool) {
+	if n == nil {
+		return NetrcMachine{}, false
+	}
+	m, ok := n.machines[strings.ToLower(host)]
+	return m, ok
+}
+
+// Lookup учётные данные для хоста; запись default подходит любому хосту
+func (n *Netrc) Lookup(host string) (NetrcMachine, bool) {
+	if m, ok := n.Machine(host); ok {
+		return m, true
+	}
+	if n != nil && n.fallback != nil {
+		return *n.fallback, true
+	}
+	return NetrcMachine{}, false
+}
@@ -0,0 +1,64 @@
+package downloader
+
+import (
+	"fmt"
+	"golang.org/x/net/http/httpproxy"
+	"net/http"
+	"net/url"
+)
+
+// Proxy настройки прокси. Незаданные значения берутся из HTTP_PROXY, HTTPS_PROXY и NO_PROXY
+type Proxy struct {
+	URL      *url.URL      // прокси для всех схем; nil - из окружения
+	NoProxy  string        // хосты и домены в формате NO_PROXY, к которым подключаться напрямую
+	Disabled bool          // не использовать прокси, даже заданный в окружении
+	User     *url.Userinfo // логин и пароль прокси вместо указанных в URL
+}
+
+// ParseProxyURL разбирает URL прокси (http, https, socks5, socks5h)
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		// как curl, адрес без схемы считается HTTP прокси
+		u, err = url.Parse("http://" + raw)
+	}
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", raw)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q: expected http, https or socks5", u.Scheme)
+	}
+	return u, nil
+}
+
+// proxyFunc функция выбора прокси для http.Transport
+func (p *Proxy) proxyFunc() func(*http.Request) (*url.URL, error) {
+	if p == nil {
+		return http.ProxyFromEnvironment
+	}
+	if p.Disabled {
+		return nil
+	}
+
+	config := httpproxy.FromEnvironment()
+	if p.URL != nil {
+		config.HTTPProxy = p.URL.String()
+		config.HTTPSProxy = p.URL.String()
+	}
+	if p.NoProxy != "" {
+		config.NoProxy = p.NoProxy
+	}
+
+	proxy := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		u, err := proxy(req.URL)
+		if u == nil || err != nil || p.User == nil {
+			return u, err
+		}
+		withUser := *u
+		withUser.User = p.User
+		return &withUser, nil
+	}
+}
@@ -0,0 +1,172 @@
+package downloader
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestParseProxyURL тест разбора адреса прокси
+func TestParseProxyURL(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+		ok       bool
+	}{
+		{"http://proxy.corp:3128", "http://proxy.corp:3128", true},
+		{"proxy.corp:3128", "http://proxy.corp:3128", true},
+		{"socks5://bob:pw@gw.corp:1080", "socks5://bob:pw@gw.corp:1080", true},
+		{"ftp://proxy.corp", "", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		u, err := ParseProxyURL(test.raw)
+		if (err == nil) != test.ok || test.ok && u.String() != test.expected {
+			t.Errorf("%q: expected %q (ok %v), got %v, %v", test.raw, test.expected, test.ok, u, err)
+		}
+	}
+}
+
+// TestProxyNoProxy тест выбора прокси с учётом списка NO_PROXY
+func TestProxyNoProxy(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.corp:3128")
+	proxy := (&Proxy{URL: proxyURL, NoProxy: "internal.corp,.intranet"}).proxyFunc()
+
+	tests := map[string]bool{
+		"https://docs.example.com/":  true,
+		"http://internal.corp/page":  false,
+		"http://wiki.intranet/page":  false,
+		"http://docs.internal.corp/": false,
+	}
+	for raw, proxied := range tests {
+		req, _ := http.NewRequest(http.MethodGet, raw, nil)
+		u, err := proxy(req)
+		if err != nil || (u != nil) != proxied {
+			t.Errorf("%s: expected proxied %v, got %v, %v", raw, proxied, u, err)
+		}
+	}
+
+	if (&Proxy{URL: proxyURL, Disabled: true}).proxyFunc() != nil {
+		t.Error("expected no proxy when disabled")
+	}
+}
+
+// TestProxyHTTP тест запроса через HTTP прокси с аутентификацией
+func TestProxyHTTP(t *testing.T) {
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "Basic YWxpY2U6czNjcmV0" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if r.URL.String() != "http://docs.example.test/page" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("via proxy"))
+	}))
+	defer proxySrv.Close()
+
+	proxyURL, _ := ParseProxyURL(proxySrv.URL)
+	client := NewClient(Options{Proxy: &Proxy{URL: proxyURL, User: url.UserPassword("alice", "s3cret")}})
+	resp, err := client.Get(context.Background(), "http://docs.example.test/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "via proxy" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+// TestProxySOCKS5 тест запроса через SOCKS5 прокси с логином и паролем
+func TestProxySOCKS5(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	target := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		target <- _serveSOCKS5(conn, "alice", "s3cret")
+	}()
+
+	proxyURL, _ := ParseProxyURL("socks5://alice:s3cret@" + ln.Addr().String())
+	client := NewClient(Options{Proxy: &Proxy{URL: proxyURL}})
+	resp, err := client.Get(context.Background(), "http://docs.example.test:8080/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "via socks" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if addr := <-target; addr != "docs.example.test:8080" {
+		t.Errorf("unexpected CONNECT target %q", addr)
+	}
+}
+
+// _serveSOCKS5 минимальный SOCKS5 сервер: проверяет логин и пароль и отвечает на один HTTP запрос
+func _serveSOCKS5(conn net.Conn, user, password string) string {
+	r := bufio.NewReader(conn)
+	read := func(n int) []byte {
+		b := make([]byte, n)
+		io.ReadFull(r, b)
+		return b
+	}
+
+	// приветствие: версия, методы; выбираем логин и пароль
+	greeting := read(2)
+	read(int(greeting[1]))
+	conn.Write([]byte{5, 2})
+
+	// RFC 1929: версия, логин, пароль
+	read(1)
+	gotUser := string(read(int(read(1)[0])))
+	gotPassword := string(read(int(read(1)[0])))
+	if gotUser != user || gotPassword != password {
+		conn.Write([]byte{1, 1})
+		return ""
+	}
+	conn.Write([]byte{1, 0})
+
+	// CONNECT с доменным именем
+	header := read(4)
+	var host string
+	switch header[3] {
+	case 3:
+		host = string(read(int(read(1)[0])))
+	case 1:
+		host = net.IP(read(4)).String()
+	}
+	port := binary.BigEndian.Uint16(read(2))
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return ""
+	}
+	req.Body.Close()
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		ContentLength: 9,
+		Body:          io.NopCloser(strings.NewReader("via socks")),
+	}
+	resp.Write(conn)
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
@@ -0,0 +1,98 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// minRateChunk наименьшая порция, которой читается тело при ограничении скорости
+const minRateChunk = 1024
+
+// RateLimit ограничивает общую скорость загрузки тел ответов rate байтами в секунду (--limit-rate).
+// Скорость считается по байтам, полученным по сети, до раскодирования; ответы не из сети,
+// например из кеша, не ограничиваются. rate <= 0 - без ограничения
+func RateLimit(rate int64) Middleware {
+	limiter := newByteLimiter(rate)
+	return func(next Fetcher) Fetcher {
+		if limiter == nil {
+			return next
+		}
+		return FetcherFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			resp, err := next.Fetch(ctx, req)
+			if err != nil || resp.idle == nil {
+				return resp, err
+			}
+			resp.Body = &rateReader{body: resp.Body, idle: resp.idle, limiter: limiter, ctx: ctx, counted: resp.idle.read}
+			return resp, nil
+		})
+	}
+}
+
+// byteLimiter token bucket на байты, общий для всех запросов (--limit-rate)
+type byteLimiter struct {
+	rate   float64 // байт в секунду
+	burst  float64
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newByteLimiter инициализирует byteLimiter; nil, если rate <= 0
+func newByteLimiter(rate int64) *byteLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	// порция в десятую долю секунды: скорость выравнивается, а пауза между чтениями короткая
+	burst := max(float64(rate)/10, minRateChunk)
+	return &byteLimiter{rate: float64(rate), burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve списывает n байт и возвращает паузу, после которой их можно читать дальше.
+// Долг переходит на следующие чтения всех запросов, поэтому общая скорость не превышает rate
+func (l *byteLimiter) reserve(n int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// rateReader читает тело ответа не быстрее, чем разрешает limiter. body может раскодировать
+// тело, поэтому списываются байты, которые за время чтения прочитал idle
+type rateReader struct {
+	body    io.ReadCloser
+	idle    *idleReader
+	limiter *byteLimiter
+	ctx     context.Context
+	counted int64 // сколько байт idle уже списано
+}
+
+// Read реализует io.Reader
+func (r *rateReader) Read(p []byte) (int, error) {
+	if len(p) > int(r.limiter.burst) {
+		p = p[:int(r.limiter.burst)]
+	}
+	n, err := r.body.Read(p)
+	received := r.idle.read - r.counted
+	r.counted = r.idle.read
+	if delay := r.limiter.reserve(received); delay > 0 {
+		// пауза ограничения скорости - не простой соединения
+		if err := r.idle.sleep(r.ctx, delay); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// Close реализует io.Closer
+func (r *rateReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,40 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestGetLimitRate тест ограничения скорости загрузки
+func TestGetLimitRate(t *testing.T) {
+	page := bytes.Repeat([]byte("x"), 60<<10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	// таймаут простоя короче пауз ограничения скорости: паузы простоем не считаются
+	client := NewClient(Options{Timeouts: Timeouts{Read: 50 * time.Millisecond}})
+	fetcher := Wrap(client, RateLimit(100<<10))
+	start := time.Now()
+	resp, err := fetcher.Fetch(context.Background(), &Request{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	elapsed := time.Since(start)
+
+	if err != nil || !bytes.Equal(body, page) {
+		t.Fatalf("unexpected body (%d bytes), %v", len(body), err)
+	}
+	// 60K при 100K/s за вычетом начального запаса в 10K - не быстрее 0.5 секунды
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("expected download limited to 100K/s, took %v", elapsed)
+	}
+}
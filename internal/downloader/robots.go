@@ -1,10 +1,12 @@
 package downloader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
+	"sync"
 
 	"github.com/temoto/robotstxt"
 )
@@ -14,33 +16,37 @@ const UserAgent = "mirror-wget/0.1 (+https://github.com/1337yeeee/mirror-wget)"
 
 // Robots структура для работы с robots.txt
 type Robots struct {
-	data *robotstxt.RobotsData
+	data  *robotstxt.RobotsData
+	agent string
 }
 
 // LoadRobots загружает robots.txt для данного базового URL.
-func LoadRobots(base *url.URL) (*Robots, error) {
+// Запрос выполняется fetcher обхода - с теми же учётными данными и cookies; правила
+// выбираются для agent
+func LoadRobots(ctx context.Context, base *url.URL, fetcher Fetcher, agent string) (*Robots, error) {
 	robotsURL := fmt.Sprintf("%s://%s/robots.txt", base.Scheme, base.Host)
-	resp, err := http.Get(robotsURL)
+	resp, err := fetcher.Fetch(ctx, &Request{URL: robotsURL})
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		// Если файл не найден, или запрещён — политика по умолчанию
+		// Например, при 404 считаем всё разрешённым
+		return &Robots{data: nil, agent: agent}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-		rdata, err := robotstxt.FromBytes(body)
-		if err != nil {
-			return nil, err
-		}
-		return &Robots{data: rdata}, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
-
-	// Если файл не найден, или запрещён, или ошибка — политика по умолчанию
-	// Например, при 404 считаем всё разрешённым
-	return &Robots{data: nil}, nil
+	rdata, err := robotstxt.FromBytes(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Robots{data: rdata, agent: agent}, nil
 }
 
 // Allowed проверяет путь URL — разрешён ли он.
@@ -48,5 +54,90 @@ func (r *Robots) Allowed(u *url.URL) bool {
 	if r == nil || r.data == nil {
 		return true
 	}
-	return r.data.TestAgent(u.Path, UserAgent)
+	return r.data.TestAgent(u.Path, r.agent)
+}
+
+// RobotsCache хранит robots.txt для каждого origin (схемы, хоста и порта), загружая их
+// по требованию. Загрузка идёт без блокировки кеша: пока robots.txt одного хоста
+// загружается, правила других хостов доступны
+type RobotsCache struct {
+	fetcher Fetcher
+	agent   string
+	mu      sync.Mutex
+	robots  map[string]*robotsEntry
+}
+
+// robotsEntry robots.txt origin, загруженный или загружаемый
+type robotsEntry struct {
+	done   chan struct{} // закрывается после загрузки
+	robots *Robots
+	err    error
+}
+
+// NewRobotsCache инициализирует RobotsCache
+func NewRobotsCache(fetcher Fetcher, agent string) *RobotsCache {
+	return &RobotsCache{
+		fetcher: fetcher,
+		agent:   agent,
+		robots:  make(map[string]*robotsEntry),
+	}
+}
+
+// entry запись origin URL; если robots.txt ещё не загружался, загрузка запускается
+// в фоне с контекстом ctx, чтобы её прерывала отмена задания
+func (c *RobotsCache) entry(ctx context.Context, u *url.URL) *robotsEntry {
+	key := Origin(u)
+	c.mu.Lock()
+	e, ok := c.robots[key]
+	if !ok {
+		e = &robotsEntry{done: make(chan struct{})}
+		c.robots[key] = e
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		go func() {
+			e.robots, e.err = LoadRobots(ctx, u, c.fetcher, c.agent)
+			close(e.done)
+		}()
+	}
+	return e
+}
+
+// Ready загружен ли robots.txt origin URL. Не блокирует: если robots.txt ещё не
+// загружался, запускает загрузку
+func (c *RobotsCache) Ready(ctx context.Context, u *url.URL) bool {
+	if c == nil {
+		return true
+	}
+	select {
+	case <-c.entry(ctx, u).done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Load загружает robots.txt origin URL, если он ещё не загружен, и ждёт окончания загрузки
+func (c *RobotsCache) Load(ctx context.Context, u *url.URL) (*Robots, error) {
+	e := c.entry(ctx, u)
+	select {
+	case <-e.done:
+		return e.robots, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Allowed проверяет URL по robots.txt его origin.
+// Если robots.txt загрузить не удалось, всё считается разрешённым
+func (c *RobotsCache) Allowed(ctx context.Context, u *url.URL) bool {
+	if c == nil {
+		return true
+	}
+	r, err := c.Load(ctx, u)
+	if err != nil {
+		return true
+	}
+	return r.Allowed(u)
 }
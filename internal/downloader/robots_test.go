@@ -0,0 +1,84 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRobotsCacheSlowHost тест: зависший robots.txt одного хоста не задерживает
+// проверку других хостов, а отмена контекста прерывает его загрузку
+func TestRobotsCacheSlowHost(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	}))
+	defer fast.Close()
+
+	client := NewClient(Options{})
+	robots := NewRobotsCache(client, client.UserAgent())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slowURL, _ := url.Parse(slow.URL + "/page")
+	if robots.Ready(ctx, slowURL) {
+		t.Fatal("expected robots.txt of the slow host to be loading")
+	}
+	result := make(chan bool)
+	go func() { result <- robots.Allowed(ctx, slowURL) }()
+
+	fastURL, _ := url.Parse(fast.URL + "/private/page")
+	done := make(chan bool)
+	go func() { done <- robots.Allowed(ctx, fastURL) }()
+	select {
+	case allowed := <-done:
+		if allowed {
+			t.Error("expected /private/ to be disallowed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("robots.txt of a slow host blocked another host")
+	}
+
+	cancel()
+	select {
+	case allowed := <-result:
+		if !allowed {
+			t.Error("expected everything allowed when robots.txt cannot be loaded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled context did not stop loading robots.txt")
+	}
+}
+
+// TestRobotsCacheScheme тест: правила https не применяются к http того же хоста и порта
+func TestRobotsCacheScheme(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	}))
+	defer srv.Close()
+
+	client := NewClient(Options{TLS: srv.Client().Transport.(*http.Transport).TLSClientConfig})
+	robots := NewRobotsCache(client, client.UserAgent())
+	secure, _ := url.Parse(srv.URL + "/private/page")
+	if robots.Allowed(context.Background(), secure) {
+		t.Error("expected https /private/ to be disallowed")
+	}
+	// на http сервер отвечает 400, robots.txt для http нет
+	plain, _ := url.Parse(strings.Replace(srv.URL, "https://", "http://", 1) + "/private/page")
+	if !robots.Allowed(context.Background(), plain) {
+		t.Error("expected robots.txt of https not to apply to http")
+	}
+}
@@ -0,0 +1,158 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Значения таймаутов по умолчанию. Таймаута на всю загрузку нет: медленная загрузка,
+// которая продолжает получать данные, должна завершиться
+const (
+	DefaultConnectTimeout  = 30 * time.Second
+	DefaultTLSTimeout      = 30 * time.Second
+	DefaultResponseTimeout = 60 * time.Second
+	DefaultReadTimeout     = 900 * time.Second
+)
+
+// Timeouts таймауты этапов запроса; нулевое значение - значение по умолчанию
+type Timeouts struct {
+	DNS      time.Duration // разрешение имени; без него ограничено только Connect
+	Connect  time.Duration // установка TCP соединения
+	TLS      time.Duration // TLS рукопожатие
+	Response time.Duration // от отправки запроса до заголовков ответа
+	Read     time.Duration // простой при чтении тела: загрузка прерывается, если данных нет дольше
+}
+
+// WithDefaults заполняет незаданные таймауты значением all (-T), а если оно не задано - значениями по умолчанию
+func (t Timeouts) WithDefaults(all time.Duration) Timeouts {
+	fill := func(value *time.Duration, def time.Duration) {
+		switch {
+		case *value > 0:
+		case all > 0:
+			*value = all
+		default:
+			*value = def
+		}
+	}
+	fill(&t.DNS, 0)
+	fill(&t.Connect, DefaultConnectTimeout)
+	fill(&t.TLS, DefaultTLSTimeout)
+	fill(&t.Response, DefaultResponseTimeout)
+	fill(&t.Read, DefaultReadTimeout)
+	return t
+}
+
+// dialContext устанавливает соединение, ограничивая разрешение имени таймаутом DNS,
+// а каждую попытку соединения - таймаутом Connect
+func (t Timeouts) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || t.DNS <= 0 || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		dnsCtx, cancel := context.WithTimeout(ctx, t.DNS)
+		ips, err := net.DefaultResolver.LookupHost(dnsCtx, host)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range ips {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// idleTimeoutError загрузка прервана, потому что данные не приходили дольше таймаута чтения.
+// Реализует net.Error, поэтому считается сетевой ошибкой и повторяется
+type idleTimeoutError struct {
+	timeout time.Duration
+}
+
+// Error реализует error
+func (e *idleTimeoutError) Error() string {
+	return fmt.Sprintf("read timeout: no data for %s", e.timeout)
+}
+
+// Timeout реализует net.Error
+func (e *idleTimeoutError) Timeout() bool { return true }
+
+// Temporary реализует net.Error
+func (e *idleTimeoutError) Temporary() bool { return true }
+
+// idleReader тело ответа, которое отменяет запрос, если чтение простаивает дольше timeout
+type idleReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	read    int64 // прочитано байт
+
+	mu      sync.Mutex
+	expired bool
+}
+
+// newIdleReader инициализирует idleReader; таймер запускается сразу
+func newIdleReader(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleReader {
+	r := &idleReader{body: body, timeout: timeout, cancel: cancel}
+	r.timer = time.AfterFunc(timeout, r.expire)
+	return r
+}
+
+// expire отменяет запрос по истечении таймаута
+func (r *idleReader) expire() {
+	r.mu.Lock()
+	r.expired = true
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// Read реализует io.Reader; каждая порция данных перезапускает таймер
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.read += int64(n)
+
+	r.mu.Lock()
+	expired := r.expired
+	r.mu.Unlock()
+	if expired {
+		return n, &idleTimeoutError{timeout: r.timeout}
+	}
+
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+// sleep выдерживает паузу d, которую требует сам клиент: таймер простоя на это время остановлен
+func (r *idleReader) sleep(ctx context.Context, d time.Duration) error {
+	r.timer.Stop()
+	defer r.timer.Reset(r.timeout)
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close реализует io.Closer
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.body.Close()
+}
@@ -0,0 +1,105 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestTimeoutsWithDefaults тест заполнения незаданных таймаутов
+func TestTimeoutsWithDefaults(t *testing.T) {
+	got := Timeouts{Read: time.Minute}.WithDefaults(0)
+	expect := Timeouts{
+		Connect:  DefaultConnectTimeout,
+		TLS:      DefaultTLSTimeout,
+		Response: DefaultResponseTimeout,
+		Read:     time.Minute,
+	}
+	if got != expect {
+		t.Errorf("expected %+v, got %+v", expect, got)
+	}
+
+	got = Timeouts{Connect: time.Second}.WithDefaults(5 * time.Second)
+	expect = Timeouts{DNS: 5 * time.Second, Connect: time.Second, TLS: 5 * time.Second, Response: 5 * time.Second, Read: 5 * time.Second}
+	if got != expect {
+		t.Errorf("expected %+v, got %+v", expect, got)
+	}
+}
+
+// _chunkedServer сервер, отдающий chunks порций с паузой interval между ними, затем stall
+func _chunkedServer(chunks int, interval, stall time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for n := 0; n < chunks; n++ {
+			w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+			time.Sleep(interval)
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(stall):
+		}
+	}))
+}
+
+// TestGetReadTimeout тест прерывания зависшей загрузки и завершения медленной
+func TestGetReadTimeout(t *testing.T) {
+	client := NewClient(Options{Timeouts: Timeouts{Read: 500 * time.Millisecond}})
+
+	// медленная загрузка: порции приходят чаще таймаута, хотя вся загрузка длится дольше него
+	slow := _chunkedServer(6, 100*time.Millisecond, 0)
+	defer slow.Close()
+	resp, err := client.Get(context.Background(), slow.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := resp.Body
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || len(data) != 30 {
+		t.Errorf("expected slow download to finish, got %d bytes, %v", len(data), err)
+	}
+
+	// зависшая загрузка
+	stalled := _chunkedServer(1, 0, 5*time.Second)
+	defer stalled.Close()
+	resp, err = client.Get(context.Background(), stalled.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = resp.Body
+	start := time.Now()
+	_, err = io.ReadAll(body)
+	body.Close()
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("expected read timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("stalled download was aborted after %v", elapsed)
+	}
+}
+
+// TestGetResponseTimeout тест ожидания заголовков ответа
+func TestGetResponseTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(Options{Timeouts: Timeouts{Response: 100 * time.Millisecond}})
+	_, err := client.Get(context.Background(), srv.URL)
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("expected response header timeout, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package downloader
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestParseTLSVersion тест разбора минимальной версии TLS
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected uint16
+		ok       bool
+	}{
+		{"", 0, true},
+		{"1.2", tls.VersionTLS12, true},
+		{"TLSv1_3", tls.VersionTLS13, true},
+		{"tlsv1.1", tls.VersionTLS11, true},
+		{"1.0", tls.VersionTLS10, true},
+		{"SSLv3", 0, false},
+	}
+	for _, test := range tests {
+		v, err := ParseTLSVersion(test.raw)
+		if (err == nil) != test.ok || v != test.expected {
+			t.Errorf("%q: expected %x (ok %v), got %x, %v", test.raw, test.expected, test.ok, v, err)
+		}
+	}
+}
+
+// TestTLSCACertificate тест проверки сервера по своему центру сертификации
+func TestTLSCACertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	_writePEM(t, caFile, "CERTIFICATE", srv.Certificate().Raw)
+	caDir := filepath.Join(dir, "certs")
+	os.Mkdir(caDir, 0o755)
+	_writePEM(t, filepath.Join(caDir, "5ed36f99.0"), "CERTIFICATE", srv.Certificate().Raw)
+
+	tests := []struct {
+		name string
+		opts TLSOptions
+		ok   bool
+	}{
+		{"system roots", TLSOptions{}, false},
+		{"ca certificate", TLSOptions{CACertificate: caFile}, true},
+		{"ca directory", TLSOptions{CADirectory: caDir}, true},
+		{"insecure", TLSOptions{Insecure: true}, true},
+	}
+	for _, test := range tests {
+		config, err := NewTLSConfig(test.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		client := NewClient(Options{TLS: config})
+		resp, err := client.Get(context.Background(), srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err == nil) != test.ok {
+			t.Errorf("%s: expected ok %v, got %v", test.name, test.ok, err)
+		}
+	}
+
+	if _, err := NewTLSConfig(TLSOptions{CADirectory: t.TempDir()}); err == nil {
+		t.Error("expected error for directory without certificates")
+	}
+}
+
+// TestTLSClientCertificate тест аутентификации клиента по сертификату (mTLS)
+func TestTLSClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := _writeClientCert(t, dir)
+	clientCert, _ := tls.LoadX509KeyPair(certFile, keyFile)
+	leaf, _ := x509.ParseCertificate(clientCert.Certificate[0])
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	config, err := NewTLSConfig(TLSOptions{Insecure: true, Certificate: certFile, PrivateKey: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := NewClient(Options{TLS: config}).Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	config, _ = NewTLSConfig(TLSOptions{Insecure: true})
+	if resp, err := NewClient(Options{TLS: config}).Get(context.Background(), srv.URL); err == nil {
+		resp.Body.Close()
+		t.Error("expected handshake error without client certificate")
+	}
+
+	if _, err := NewTLSConfig(TLSOptions{PrivateKey: keyFile}); err == nil {
+		t.Error("expected error for private key without certificate")
+	}
+}
+
+// _writeClientCert создаёт самоподписанный клиентский сертификат и ключ
+func _writeClientCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "crawler"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	_writePEM(t, certFile, "CERTIFICATE", der)
+	_writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+// _writePEM записывает блок PEM в файл
+func _writePEM(t *testing.T, path, blockType string, der []byte) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
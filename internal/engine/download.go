@@ -0,0 +1,406 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mirror-wget/internal/downloader"
+	"mirror-wget/internal/journal"
+	"mirror-wget/internal/logging"
+	"mirror-wget/internal/normalizer"
+	"mirror-wget/internal/queue"
+	"mirror-wget/internal/storage"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// MaxParseSize сколько первых байт HTML или CSS документа передаётся парсеру.
+// Документ сохраняется целиком, но ссылки после этой границы не извлекаются
+const MaxParseSize = 16 << 20
+
+// download результат загрузки документа
+type download struct {
+	status      int // код ответа: 200, 206 при продолжении загрузки, 304, если документ не изменился
+	contentType string
+	size        int64
+	sha256      string
+	path        string // пустой, если документ не сохранялся
+	prefix      []byte // начало документа для извлечения ссылок; nil, если ссылки не извлекаются
+	truncated   bool   // документ длиннее MaxParseSize
+	validators  downloader.Validators
+	notModified bool                      // документ не изменился с прошлого обхода и взят с диска
+	url         *normalizer.NormalizedURL // адрес после редиректов; nil, если редиректов не было
+	redirects   []string                  // промежуточные адреса цепочки редиректов
+	encoding    string                    // Content-Encoding сохранённого файла; пустой, если файл раскодирован
+}
+
+// downloadFile скачивает документ потоком: в файл, если save, в хеш и, для HTML и CSS,
+// в ограниченный буфер для парсера. Документ целиком в памяти не хранится.
+// При -c загрузка продолжается с конца временного файла прерванной загрузки,
+// при -N документ, не изменившийся с прошлого обхода, берётся с диска
+func (w *Worker) downloadFile(ctx context.Context, item queue.Item, save bool) (*download, error) {
+	var file *storage.File
+	var info storage.PartInfo
+	var prev *journal.Entry
+	if save {
+		filePath, err := item.URL.SavePathIn(w.layout)
+		if err != nil {
+			return nil, &saveError{fmt.Errorf("save path failed: %s - %v", item.URL.String(), err)}
+		}
+		if w.resume {
+			file, info = w.openPart(filePath)
+		}
+		if entry, ok := w.previous.Lookup(item.URL.String()); ok && file == nil {
+			prev = &entry
+		}
+		if file == nil {
+			file, err = storage.Create(filePath)
+			if err != nil {
+				return nil, &saveError{fmt.Errorf("save failed: %s - %v", filePath, err)}
+			}
+		}
+	}
+
+	generation := w.session.Generation()
+	result, err := w.fetch(ctx, item, file, info, prev)
+	if errors.Is(err, errSessionExpired) {
+		// сессия истекла: вход повторяется, и документ запрашивается ещё раз
+		if err = w.session.Refresh(ctx, generation); err == nil {
+			result, err = w.fetch(ctx, item, file, info, prev)
+		}
+		if errors.Is(err, errSessionExpired) {
+			err = &LoginError{URL: item.URL.String(), Reason: "session expired again right after logging in"}
+		}
+	}
+	if err == nil && result.notModified {
+		file.Abort()
+		reused, err := w.reuse(item, *prev)
+		if err != nil {
+			return nil, err
+		}
+		reused.status, reused.url, reused.redirects = result.status, result.url, result.redirects
+		return reused, nil
+	}
+	if err != nil {
+		if file != nil {
+			var se *saveError
+			var tle *tooLargeError
+			var ratioErr *downloader.RatioError
+			if w.resume && !errors.As(err, &se) && !errors.As(err, &tle) && !errors.As(err, &ratioErr) {
+				// временный файл остаётся, чтобы следующая попытка продолжила загрузку
+				file.Close()
+			} else {
+				file.Abort()
+			}
+		}
+		return nil, err
+	}
+
+	if file != nil {
+		// документ сохраняется по адресу, на который привели редиректы
+		path := file.Path()
+		if result.url != nil {
+			if finalPath, err := result.url.SavePathIn(w.layout); err == nil {
+				path = finalPath
+			}
+		}
+		if err := file.CommitAs(path); err != nil {
+			return nil, &saveError{fmt.Errorf("save failed: %s - %v", file.Path(), err)}
+		}
+		result.path = file.Path()
+		// как wget, время изменения файла совпадает с Last-Modified документа
+		if modified, err := http.ParseTime(result.validators.LastModified); err == nil {
+			os.Chtimes(result.path, modified, modified)
+		}
+	}
+	return result, nil
+}
+
+// reuse возвращает сохранённый прошлым обходом документ; для HTML и CSS читает
+// начало файла, чтобы пройти по ссылкам неизменившейся страницы
+func (w *Worker) reuse(item queue.Item, prev journal.Entry) (*download, error) {
+	result := &download{
+		contentType: prev.ContentType,
+		size:        prev.Size,
+		sha256:      prev.SHA256,
+		path:        prev.Path,
+		validators:  downloader.Validators{ETag: prev.ETag, LastModified: prev.LastModified},
+		notModified: true,
+		encoding:    prev.Encoding,
+	}
+	if !downloader.IsHTML(prev.ContentType) && !downloader.IsCSS(prev.ContentType) {
+		return result, nil
+	}
+
+	f, err := os.Open(prev.Path)
+	if err != nil {
+		return nil, &saveError{fmt.Errorf("read failed: %s - %v", prev.Path, err)}
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if prev.Encoding != "" {
+		if r, err = downloader.NewDecoder(prev.Encoding, f); err != nil {
+			return nil, &saveError{fmt.Errorf("read failed: %s - %v", prev.Path, err)}
+		}
+	}
+	prefix := &prefixBuffer{limit: MaxParseSize}
+	if _, err := io.Copy(prefix, r); err != nil {
+		return nil, &saveError{fmt.Errorf("read failed: %s - %v", prev.Path, err)}
+	}
+	result.prefix = prefix.buf
+	result.truncated = prefix.truncated
+	return result, nil
+}
+
+// openPart открывает временный файл прерванной загрузки; nil, если продолжать нечего
+func (w *Worker) openPart(filePath string) (*storage.File, storage.PartInfo) {
+	file, info, err := storage.OpenPart(filePath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("cannot resume download", "path", filePath, "error", err)
+		}
+		return nil, info
+	}
+	if file.Offset() == 0 || info.Validator == "" {
+		file.Close()
+		return nil, info
+	}
+	return file, info
+}
+
+// fetch выполняет запрос и записывает тело ответа в file, если он задан.
+// Если задан prev, запрос условный: для неизменившегося документа возвращается notModified
+func (w *Worker) fetch(ctx context.Context, item queue.Item, file *storage.File, info storage.PartInfo, prev *journal.Entry) (*download, error) {
+	// таймауты этапов запроса задаются в downloader.Client, который оборачивает fetcher
+	slog.Debug("downloading", logging.KeyURL, item.URL.String(), logging.KeyDepth, item.Depth, logging.KeyStage, "download")
+	ctx = downloader.WithReferer(ctx, item.Referer)
+	ctx = downloader.WithRedirectCheck(ctx, func(to *url.URL) bool {
+		// редирект на страницу входа нужен, чтобы заметить истёкшую сессию
+		return w.redirects.Allow(item, to) || w.session.IsLoginPage(to)
+	})
+	req := &downloader.Request{URL: item.URL.String()}
+	switch {
+	case prev != nil:
+		req.Validators = downloader.Validators{ETag: prev.ETag, LastModified: prev.LastModified}
+	case file != nil:
+		req.Offset, req.Validator = file.Offset(), info.Validator
+	}
+	resp, err := w.fetcher.Fetch(ctx, req)
+	if err == nil && resp.NotModified {
+		result := &download{status: resp.Status, notModified: true}
+		result.url, result.redirects = redirected(item, resp)
+		return result, nil
+	}
+	if err == nil && resp.Offset > 0 {
+		if validator := resp.Validator(); validator != "" && validator != info.Validator {
+			// сервер проигнорировал If-Range: дописывать чужие байты нельзя
+			resp.Body.Close()
+			resp, err = w.fetcher.Fetch(ctx, &downloader.Request{URL: item.URL.String()})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %s - %w", item.URL.String(), err)
+	}
+	defer resp.Body.Close()
+	if w.session.Expired(resp) {
+		return nil, errSessionExpired
+	}
+
+	if w.maxFileSize > 0 && resp.Offset+resp.ContentLength > w.maxFileSize {
+		return nil, &tooLargeError{limit: w.maxFileSize}
+	}
+
+	contentType := resp.ContentType
+	if resp.Offset > 0 && contentType == "" {
+		contentType = info.ContentType
+	}
+	result := &download{status: resp.Status, contentType: contentType, validators: resp.Validators()}
+	result.url, result.redirects = redirected(item, resp)
+
+	// в файл и хеш идут сохраняемые байты, парсеру - всегда раскодированные
+	hash := sha256.New()
+	writers := []io.Writer{hash}
+	var decoded []io.Writer
+
+	var prefix *prefixBuffer
+	if downloader.IsHTML(contentType) || downloader.IsCSS(contentType) {
+		prefix = &prefixBuffer{limit: MaxParseSize}
+		decoded = append(decoded, prefix)
+	}
+
+	if file != nil {
+		if resp.Offset > 0 {
+			slog.Info("resuming download", logging.KeyURL, item.URL.String(), "offset", resp.Offset)
+			// уже скачанные байты учитываются в хеше и передаются парсеру
+			if _, err := io.Copy(io.MultiWriter(append(writers, decoded...)...), file.Existing()); err != nil {
+				return nil, &saveError{fmt.Errorf("resume failed: %s - %v", file.Path(), err)}
+			}
+		} else {
+			if err := file.Truncate(); err != nil {
+				return nil, &saveError{fmt.Errorf("save failed: %s - %v", file.Path(), err)}
+			}
+			// продолжить можно только загрузку без кодирования: диапазоны запрашиваются несжатыми
+			if validator := resp.Validator(); w.resume && validator != "" && resp.ContentEncoding == "" {
+				if err := file.SetInfo(storage.PartInfo{Validator: validator, ContentType: contentType}); err != nil {
+					return nil, &saveError{fmt.Errorf("save failed: %s - %v", file.Path(), err)}
+				}
+			}
+		}
+		writers = append(writers, &saveWriter{file})
+	}
+
+	var encodedSize int64
+	if w.keepEncoded && resp.ContentEncoding != "" {
+		result.encoding = resp.ContentEncoding
+		resp.WriteEncodedTo(io.MultiWriter(append(writers, &countWriter{&encodedSize})...))
+	} else {
+		decoded = append(decoded, writers...)
+	}
+
+	var body io.Reader = resp.Body
+	if w.maxFileSize > 0 {
+		left := w.maxFileSize - resp.Offset
+		body = &sizeLimitReader{r: resp.Body, left: left, limit: w.maxFileSize}
+	}
+
+	size, err := io.Copy(io.MultiWriter(append(decoded, io.Discard)...), body)
+	if err != nil {
+		var se *saveError
+		var tle *tooLargeError
+		if errors.As(err, &se) || errors.As(err, &tle) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("download failed: %s - %w", item.URL.String(), err)
+	}
+
+	result.size = resp.Offset + size
+	if result.encoding != "" {
+		result.size = encodedSize
+	}
+	result.sha256 = hex.EncodeToString(hash.Sum(nil))
+	if prefix != nil {
+		result.prefix = prefix.buf
+		result.truncated = prefix.truncated
+	}
+	return result, nil
+}
+
+// redirected адрес документа после редиректов и промежуточные адреса цепочки;
+// nil, если ответ получен с адреса item
+func redirected(item queue.Item, resp *downloader.Response) (*normalizer.NormalizedURL, []string) {
+	if len(resp.Redirects) == 0 {
+		return nil, nil
+	}
+	final, err := normalizer.NewNormalizedURL(resp.URL)
+	if err != nil || final.String() == item.URL.String() {
+		return nil, nil
+	}
+
+	var chain []string
+	for _, raw := range resp.Redirects {
+		u, err := normalizer.NewNormalizedURL(raw)
+		if err == nil && u.String() != item.URL.String() && u.String() != final.String() {
+			chain = append(chain, u.String())
+		}
+	}
+	return final, chain
+}
+
+// prefixBuffer хранит первые limit байт записанных данных, остальные отбрасывает
+type prefixBuffer struct {
+	buf       []byte
+	limit     int
+	truncated bool
+}
+
+// Write реализует io.Writer; запись никогда не завершается ошибкой
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - len(b.buf); room < len(p) {
+		b.buf = append(b.buf, p[:max(room, 0)]...)
+		b.truncated = true
+	} else {
+		b.buf = append(b.buf, p...)
+	}
+	return len(p), nil
+}
+
+// countWriter считает записанные байты
+type countWriter struct {
+	n *int64
+}
+
+// Write реализует io.Writer
+func (c *countWriter) Write(p []byte) (int, error) {
+	*c.n += int64(len(p))
+	return len(p), nil
+}
+
+// saveError ошибка записи документа на диск, в отличие от ошибок загрузки не повторяется
+type saveError struct {
+	err error
+}
+
+// Error реализует error
+func (e *saveError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap возвращает исходную ошибку
+func (e *saveError) Unwrap() error {
+	return e.err
+}
+
+// saveWriter помечает ошибки записи в файл как saveError
+type saveWriter struct {
+	w io.Writer
+}
+
+// Write реализует io.Writer
+func (s *saveWriter) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	if err != nil {
+		return n, &saveError{err}
+	}
+	return n, nil
+}
+
+// tooLargeError документ больше --max-filesize
+type tooLargeError struct {
+	limit int64
+}
+
+// Error реализует error
+func (e *tooLargeError) Error() string {
+	return fmt.Sprintf("larger than --max-filesize %d bytes", e.limit)
+}
+
+// sizeLimitReader прерывает чтение, если данных больше limit байт
+type sizeLimitReader struct {
+	r     io.Reader
+	left  int64
+	limit int64
+}
+
+// Read реализует io.Reader
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.left < 0 {
+		return 0, &tooLargeError{limit: l.limit}
+	}
+	// читаем на байт больше остатка, чтобы отличить документ ровно в limit байт от большего
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return n, &tooLargeError{limit: l.limit}
+	}
+	return n, err
+}
@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mirror-wget/internal/downloader"
+	"mirror-wget/internal/journal"
+	"mirror-wget/internal/normalizer"
+	"mirror-wget/internal/queue"
+	"mirror-wget/internal/scope"
+	"mirror-wget/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// _newTestWorker воркер, сохраняющий документы в root. Очереди, клиент, область обхода
+// и сводка, не заданные в opts, создаются
+func _newTestWorker(root string, opts WorkerOptions) *Worker {
+	opts.Queue = queue.NewQueue()
+	opts.StorageQueue = queue.NewQueue()
+	opts.DownloadMap = &sync.Map{}
+	opts.Layout = normalizer.Layout{Prefix: root}
+	opts.Hosts = NewHostLimiter(0)
+	opts.Summary = NewSummary()
+	if opts.Fetcher == nil {
+		opts.Fetcher = downloader.NewClient(downloader.Options{})
+	}
+	if opts.Scope == nil {
+		opts.Scope = scope.NewScope(nil, false, nil, nil)
+	}
+	return NewWorker(&sync.WaitGroup{}, new(int32), opts)
+}
+
+// TestDownloadFile тест потоковой загрузки в файл и буфера для парсера
+func TestDownloadFile(t *testing.T) {
+	page := `<html><body><a href="/a">a</a></body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page))
+		case "/big.bin":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(strings.Repeat("x", 4096)))
+		case "/chunked.bin":
+			// без Content-Length размер проверяется во время загрузки
+			w.Header().Set("Content-Type", "application/octet-stream")
+			for n := 0; n < 4; n++ {
+				w.Write([]byte(strings.Repeat("x", 1024)))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	w := _newTestWorker(root, WorkerOptions{MaxFileSize: 2048})
+
+	u, _ := normalizer.NewNormalizedURL(srv.URL + "/")
+	result, err := w.downloadFile(context.Background(), queue.Item{URL: u}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(result.path)
+	if err != nil || string(data) != page {
+		t.Errorf("unexpected saved file %q, %v", data, err)
+	}
+	if string(result.prefix) != page || result.size != int64(len(page)) || result.sha256 == "" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	for _, name := range []string{"big.bin", "chunked.bin"} {
+		u, _ := normalizer.NewNormalizedURL(srv.URL + "/" + name)
+		_, err := w.downloadFile(context.Background(), queue.Item{URL: u}, true)
+
+		var tle *tooLargeError
+		if !errors.As(err, &tle) {
+			t.Errorf("%s: expected too large error, got %v", name, err)
+		}
+		matches, _ := filepath.Glob(filepath.Join(root, "*", name+"*"))
+		if len(matches) != 0 {
+			t.Errorf("%s: expected no files left, got %v", name, matches)
+		}
+	}
+}
+
+// TestPrefixBuffer тест ограничения буфера для парсера
+func TestPrefixBuffer(t *testing.T) {
+	b := &prefixBuffer{limit: 5}
+	for _, chunk := range []string{"abc", "def", "gh"} {
+		if n, err := b.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("unexpected write result %d, %v", n, err)
+		}
+	}
+	if string(b.buf) != "abcde" || !b.truncated {
+		t.Errorf("expected truncated prefix \"abcde\", got %q (truncated %v)", b.buf, b.truncated)
+	}
+}
+
+// TestDownloadFileResume тест продолжения прерванной загрузки при -c
+func TestDownloadFileResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "file.bin", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		partial   string
+		validator string
+	}{
+		{"same document", content[:300], `"v2"`},
+		{"changed document", "stale bytes", `"v1"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			w := _newTestWorker(root, WorkerOptions{Resume: true})
+
+			u, _ := normalizer.NewNormalizedURL(srv.URL + "/file.bin")
+			path, _ := u.SavePathIn(w.layout)
+			file, err := storage.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write([]byte(test.partial))
+			file.SetInfo(storage.PartInfo{Validator: test.validator})
+			file.Close()
+
+			result, err := w.downloadFile(context.Background(), queue.Item{URL: u}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || string(data) != content {
+				t.Errorf("unexpected saved file of %d bytes, %v", len(data), err)
+			}
+			sum := sha256.Sum256([]byte(content))
+			if result.size != int64(len(content)) || result.sha256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("unexpected result %+v", result)
+			}
+			if _, err := os.Stat(path + storage.MetaSuffix); !os.IsNotExist(err) {
+				t.Errorf("expected meta file to be removed, got %v", err)
+			}
+		})
+	}
+}
+
+// TestDownloadFileNotModified тест повторного обхода с -N: неизменившийся документ берётся с диска
+func TestDownloadFileNotModified(t *testing.T) {
+	page := `<html><body><a href="docs/index.html">docs</a></body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		http.ServeContent(w, r, "index.html", time.Time{}, strings.NewReader(page))
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	u, _ := normalizer.NewNormalizedURL(srv.URL + "/")
+	first, err := _newTestWorker(root, WorkerOptions{}).downloadFile(context.Background(), queue.Item{URL: u}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := _newTestWorker(root, WorkerOptions{Previous: NewPrevious(&journal.State{Done: map[string]journal.Entry{u.String(): {
+		Path:        first.path,
+		ContentType: first.contentType,
+		Size:        first.size,
+		SHA256:      first.sha256,
+		ETag:        first.validators.ETag,
+	}}})})
+	result, err := w.downloadFile(context.Background(), queue.Item{URL: u}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.notModified || result.path != first.path || result.sha256 != first.sha256 || string(result.prefix) != page {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, err := os.Stat(first.path + storage.PartSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected temp file to be removed, got %v", err)
+	}
+}
+
+// TestDownloadFileEncoded тест сохранения сжатого ответа раскодированным и как есть (--keep-encoded)
+func TestDownloadFileEncoded(t *testing.T) {
+	page := `<html><body><a href="/a">a</a></body></html>`
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(page))
+	zw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gz.Bytes())
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		keepEncoded bool
+		expected    []byte
+		encoding    string
+	}{
+		{"decoded", false, []byte(page), ""},
+		{"keep encoded", true, gz.Bytes(), "gzip"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := _newTestWorker(t.TempDir(), WorkerOptions{KeepEncoded: test.keepEncoded})
+			u, _ := normalizer.NewNormalizedURL(srv.URL + "/")
+			result, err := w.downloadFile(context.Background(), queue.Item{URL: u}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ := os.ReadFile(result.path)
+			sum := sha256.Sum256(test.expected)
+			if !bytes.Equal(data, test.expected) || result.size != int64(len(test.expected)) || result.sha256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("unexpected saved file (%d bytes), result %+v", len(data), result)
+			}
+			if string(result.prefix) != page || result.encoding != test.encoding {
+				t.Errorf("expected decoded prefix and encoding %q, got %q, %q", test.encoding, result.prefix, result.encoding)
+			}
+		})
+	}
+}
@@ -2,68 +2,507 @@ package engine
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"mirror-wget/internal/cli"
+	"mirror-wget/internal/cookies"
 	"mirror-wget/internal/downloader"
+	"mirror-wget/internal/journal"
+	"mirror-wget/internal/logging"
 	"mirror-wget/internal/normalizer"
+	"mirror-wget/internal/progress"
 	"mirror-wget/internal/queue"
+	"mirror-wget/internal/scope"
+	"net/url"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// reasonRobots причина, по которой отброшена ссылка, запрещённая robots.txt
+const reasonRobots = "disallowed by robots.txt"
+
 // SleepDuration длительность сна между проверками очереди
 const SleepDuration = 100 * time.Millisecond
 
 // Engine структура для управления dispatcher'ом
 type Engine struct {
-	baseURL     *normalizer.NormalizedURL
-	queue       queue.Queue
-	visited     *sync.Map
-	downloadMap *sync.Map
-	numWorkers  int
-	maxDepth    int
-	wg          *sync.WaitGroup
-	activeTasks int32
-	robotsTxt   *downloader.Robots
+	config       *cli.Config
+	seeds        []*normalizer.NormalizedURL
+	queue        queue.Queue
+	storageQueue queue.Queue
+	visited      *sync.Map
+	downloadMap  *sync.Map
+	numWorkers   int
+	numStorage   int
+	hosts        *HostLimiter
+	pacer        *Pacer // -w, --random-wait и --host-rate; nil - без пауз
+	maxDepth     int
+	wg           *sync.WaitGroup
+	activeTasks  int32
+	robotsTxt    *downloader.RobotsCache
+	fetcher      downloader.Fetcher
+	scope        *scope.Scope
+	layout       normalizer.Layout
+	journal      *journal.Journal
+	spider       *SpiderReport // nil, если файлы сохраняются
+	progress     *progress.Tracker
+	summary      *Summary
+	retry        *RetryPolicy
+	redirects    *RedirectPolicy
+	previous     *Previous // документы прошлого обхода для -N; nil - скачивать всё заново
+	cookies      *cookies.Jar
+	session      *Session // вход через форму; nil, если он не задан
 }
 
 // NewEngine инициализирует Engine
-func NewEngine(URL *normalizer.NormalizedURL, robotsTxt *downloader.Robots, numWorkers, maxDepth int) *Engine {
+func NewEngine(
+	config *cli.Config,
+	seeds []*normalizer.NormalizedURL,
+	robotsTxt *downloader.RobotsCache,
+	fetcher downloader.Fetcher,
+	jr *journal.Journal,
+	tracker *progress.Tracker,
+	retry *RetryPolicy) *Engine {
+	sc := scope.NewScope(config.Domains, config.NoParent, config.Accept, config.Reject)
+	for _, seed := range seeds {
+		sc.AddSeed(seed.URL)
+	}
+
+	// переписывание ссылок занимает CPU, поэтому по умолчанию воркеров столько же, сколько процессоров
+	numStorage := config.StorageWorkers
+	if numStorage <= 0 {
+		numStorage = runtime.GOMAXPROCS(0)
+	}
+
+	var spider *SpiderReport
+	if config.Spider {
+		spider = NewSpiderReport()
+	}
+
+	visited := &sync.Map{}
 	return &Engine{
-		baseURL:     URL,
-		queue:       queue.NewQueue(),
-		visited:     &sync.Map{},
-		downloadMap: &sync.Map{},
-		numWorkers:  numWorkers,
-		maxDepth:    maxDepth,
-		wg:          &sync.WaitGroup{},
-		robotsTxt:   robotsTxt,
+		config:       config,
+		seeds:        seeds,
+		queue:        queue.NewQueue(),
+		storageQueue: queue.NewQueue(),
+		visited:      visited,
+		downloadMap:  &sync.Map{},
+		numWorkers:   max(1, config.Workers),
+		numStorage:   numStorage,
+		hosts:        NewHostLimiter(config.MaxPerHost),
+		pacer:        NewPacer(time.Duration(config.Wait), config.RandomWait, config.HostRate, config.HostBurst),
+		maxDepth:     config.Level,
+		wg:           &sync.WaitGroup{},
+		robotsTxt:    robotsTxt,
+		fetcher:      fetcher,
+		scope:        sc,
+		layout: normalizer.Layout{
+			Prefix:     config.OutputPrefix,
+			NoHostDirs: config.NoHostDirs,
+			CutDirs:    config.CutDirs,
+		},
+		journal:   jr,
+		spider:    spider,
+		progress:  tracker,
+		summary:   NewSummary(),
+		retry:     retry,
+		redirects: NewRedirectPolicy(sc, visited, config.OffsiteRedirect == cli.RedirectsFollow),
+	}
+}
+
+// Handle инициализирует и запускает Engine для нового задания.
+// tracker может быть nil, если прогресс не отображается. middlewares оборачивают
+// HTTP клиент задания снаружи кеша и --limit-rate: все запросы, включая robots.txt, проходят через них
+func Handle(config *cli.Config, tracker *progress.Tracker, middlewares ...downloader.Middleware) error {
+	rawSeeds, err := config.Seeds()
+	if err != nil {
+		return err
+	}
+	if len(rawSeeds) == 0 {
+		return errors.New("no URL provided")
+	}
+
+	// в журнале сохраняются уже прочитанные стартовые URL: -i - при resume перечитать нельзя
+	stored := *config
+	stored.URLs = rawSeeds
+	stored.InputFile = ""
+	// секреты в журнал не попадают, но resume должен знать, что их нужно передать снова
+	stored.Credentials = config.Given()
+
+	// при -N валидаторы документов берутся из журнала прошлого обхода, пока он не перезаписан
+	var previous *Previous
+	if config.Timestamping && !config.Spider {
+		if state, err := journal.Load(config.OutputPrefix); err == nil {
+			previous = NewPrevious(state)
+		}
+	}
+
+	// в режиме --spider на диск ничего не пишется, в том числе журнал
+	var jr *journal.Journal
+	if !config.Spider {
+		jr, err = journal.Create(config.OutputPrefix, &stored)
+		if err != nil {
+			return err
+		}
+		defer jr.Close()
+	}
+
+	engine, err := newEngineFromConfig(&stored, jr, tracker, middlewares)
+	if err != nil {
+		return err
 	}
+	engine.previous = previous
+	// задание с ошибками загрузки всё равно завершено, resume его не продолжает
+	startErr := engine.Start()
+	if err := jr.Finish(); err != nil {
+		return err
+	}
+	return startErr
+}
+
+// ResumeOptions настройки resume
+type ResumeOptions struct {
+	// Secrets учётные данные из командной строки resume: в журнале задания они не сохраняются
+	Secrets cli.Secrets
+	// RetryFailed повторить все неудачные загрузки, а не только подходящие под --retry-on
+	RetryFailed bool
 }
 
-// Handle инициализирует и запускает Engine
-func Handle() error {
-	config, err := cli.NewConfig()
+// Resume продолжает прерванное задание, состояние которого хранится в корне зеркала root
+func Resume(root string, opts ResumeOptions, tracker *progress.Tracker, middlewares ...downloader.Middleware) error {
+	var config cli.Config
+	if err := journal.ReadConfig(root, &config); err != nil {
+		return fmt.Errorf("no job to resume in %q: %v", root, err)
+	}
+	// зеркало могли перенести или запустить resume из другого каталога
+	config.OutputPrefix = root
+	config.Secrets = opts.Secrets
+	if missing := config.MissingCredentials(); len(missing) > 0 {
+		return fmt.Errorf("credentials of the job are not saved in its journal, pass them to resume again: %s", strings.Join(missing, ", "))
+	}
+
+	state, err := journal.Load(root)
 	if err != nil {
 		return err
 	}
+	retry, err := NewRetryPolicy(config.Tries, config.RetryOn, time.Duration(config.WaitRetry))
+	if err != nil {
+		return err
+	}
+	pending := resumable(state, retry, opts.RetryFailed)
+	if state.Finished && len(pending) == 0 {
+		slog.Info("job is already complete", "dir", root, "failed", len(state.Failed))
+		return nil
+	}
 
-	normURL, err := normalizer.NewNormalizedURL(config.URL)
+	jr, err := journal.Open(root)
 	if err != nil {
 		return err
 	}
+	defer jr.Close()
 
-	robotsTxt, err := downloader.LoadRobots(normURL.URL)
+	engine, err := newEngineFromConfig(&config, jr, tracker, middlewares)
 	if err != nil {
 		return err
 	}
+	engine.Restore(state, pending)
+	startErr := engine.Start()
+	if err := jr.Finish(); err != nil {
+		return err
+	}
+	return startErr
+}
+
+// newEngineFromConfig собирает Engine и его зависимости по конфигурации
+func newEngineFromConfig(config *cli.Config, jr *journal.Journal, tracker *progress.Tracker, middlewares []downloader.Middleware) (*Engine, error) {
+	seeds := make([]*normalizer.NormalizedURL, 0, len(config.URLs))
+	for _, rawSeed := range config.URLs {
+		normURL, err := normalizer.NewNormalizedURL(rawSeed)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, normURL)
+	}
+
+	headers, err := downloader.ParseHeaders(config.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	// одно хранилище на все воркеры: cookie сессии, выставленная на первой странице, нужна остальным
+	jar := cookies.NewJar()
+	if config.LoadCookies != "" {
+		if err := jar.Load(config.LoadCookies); err != nil {
+			return nil, fmt.Errorf("load cookies: %v", err)
+		}
+		slog.Info("loaded cookies", "file", config.LoadCookies, "count", jar.Len())
+	}
+
+	auth, err := newAuth(config, seeds)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := newProxy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := newCache(config)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRedirects := config.MaxRedirect
+	if maxRedirects == 0 {
+		// --max-redirect 0, как в wget, запрещает редиректы
+		maxRedirects = -1
+	}
+
+	maxRatio := config.MaxRatio
+	if maxRatio == 0 {
+		maxRatio = -1
+	}
+
+	client := downloader.NewClient(downloader.Options{
+		UserAgent: config.UserAgent,
+		Headers:   headers,
+		Timeouts: downloader.Timeouts{
+			DNS:      time.Duration(config.DNSTimeout),
+			Connect:  time.Duration(config.ConnectTimeout),
+			TLS:      time.Duration(config.TLSTimeout),
+			Response: time.Duration(config.HeaderTimeout),
+			Read:     time.Duration(config.ReadTimeout),
+		}.WithDefaults(time.Duration(config.Timeout)),
+		Jar:          jar,
+		Auth:         auth,
+		Proxy:        proxy,
+		TLS:          tlsConfig,
+		MaxRedirects: maxRedirects,
+		// --compression none отправляет Accept-Encoding: identity
+		NoCompression: config.Compression == cli.CompressionNone,
+		MaxRatio:      maxRatio,
+	})
+
+	retry, err := NewRetryPolicy(config.Tries, config.RetryOn, time.Duration(config.WaitRetry))
+	if err != nil {
+		return nil, err
+	}
+
+	cacheOptions := downloader.CacheOptions{
+		Header: client.Header,
+		// cookies сессии входа, cookies.txt и --header "Cookie: ..." открывают личные страницы
+		AuthCookies: config.Login != nil || config.LoadCookies != "" || headers.Get("Cookie") != "",
+	}
+	if config.CacheOnly {
+		cacheOptions.Mode = downloader.CacheOffline
+	}
+	// кеш снаружи ограничения скорости: ответы из кеша скорость не расходуют
+	middlewares = append(middlewares[:len(middlewares):len(middlewares)],
+		cache.Middleware(cacheOptions),
+		downloader.RateLimit(int64(config.LimitRate)),
+	)
+	fetcher := downloader.Wrap(client, middlewares...)
+
+	// robots.txt загружаются по требованию для каждого origin, включая origin всех стартовых URL
+	var robotsTxt *downloader.RobotsCache
+	if config.Robots {
+		robotsTxt = downloader.NewRobotsCache(fetcher, client.UserAgent())
+	}
+
+	slog.Info("starting job", "max_depth", config.Level, "seeds", len(seeds), "workers", config.Workers)
+	login := config.Login
+	if login != nil && config.CacheOnly {
+		// без сети вход невозможен; страницы сессии попали в кеш, только если сервер
+		// разрешил их хранить (public), поэтому из кеша собираются в основном открытые страницы
+		slog.Info("login skipped with --cache-only")
+		login = nil
+	}
+	session, err := NewSession(login, loginPassword(config), fetcher)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := NewEngine(config, seeds, robotsTxt, fetcher, jr, tracker, retry)
+	engine.cookies = jar
+	engine.session = session
+	return engine, nil
+}
+
+// newAuth собирает учётные данные; они отправляются только хостам стартовых URL
+// и хостам из .netrc. nil, если учётных данных нет
+func newAuth(config *cli.Config, seeds []*normalizer.NormalizedURL) (*downloader.Auth, error) {
+	headers, err := downloader.ParseHeaders(config.AuthHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var netrc *downloader.Netrc
+	if !config.NoNetrc {
+		netrc, err = downloader.LoadNetrc(downloader.NetrcPath())
+		if err != nil {
+			return nil, fmt.Errorf("read .netrc: %v", err)
+		}
+	}
+
+	if config.User == "" && config.BearerToken == "" && len(headers) == 0 && netrc == nil {
+		return nil, nil
+	}
+
+	origins := make([]string, 0, len(seeds))
+	for _, seed := range seeds {
+		origins = append(origins, downloader.Origin(seed.URL))
+	}
+	return &downloader.Auth{
+		User:        config.User,
+		Password:    config.Password,
+		Token:       config.BearerToken,
+		Headers:     headers,
+		Origins:     origins,
+		Netrc:       netrc,
+		NoChallenge: config.AuthNoChallenge,
+	}, nil
+}
+
+// newProxy собирает настройки прокси; без --proxy прокси берётся из HTTP_PROXY и HTTPS_PROXY
+func newProxy(config *cli.Config) (*downloader.Proxy, error) {
+	proxy := &downloader.Proxy{NoProxy: config.NoProxyHosts, Disabled: config.NoProxy}
+	if config.Proxy != "" && !config.NoProxy {
+		u, err := downloader.ParseProxyURL(config.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxy.URL = u
+	}
+	if config.ProxyUser != "" {
+		proxy.User = url.UserPassword(config.ProxyUser, config.ProxyPassword)
+	}
+	return proxy, nil
+}
+
+// newTLSConfig собирает настройки TLS из --ca-certificate, --certificate и других флагов
+func newTLSConfig(config *cli.Config) (*tls.Config, error) {
+	minVersion, err := downloader.ParseTLSVersion(config.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	if config.NoCheckCert {
+		slog.Warn("server certificates are not checked (--no-check-certificate)")
+	}
+	return downloader.NewTLSConfig(downloader.TLSOptions{
+		CACertificate: config.CACertificate,
+		CADirectory:   config.CADirectory,
+		Certificate:   config.Certificate,
+		PrivateKey:    config.PrivateKey,
+		Insecure:      config.NoCheckCert,
+		MinVersion:    minVersion,
+	})
+}
+
+// loginPassword пароль для входа через форму
+func loginPassword(config *cli.Config) string {
+	if config.Login == nil {
+		return ""
+	}
+	return config.Login.Secret(config.Password)
+}
+
+// newCache открывает кеш ответов; nil при --no-cache. Без --cache-dir кеш общий
+// для всех заданий пользователя. В режиме --spider кеш только читается
+func newCache(config *cli.Config) (*downloader.Cache, error) {
+	if config.NoCache {
+		return nil, nil
+	}
+	dir := config.CacheDir
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			if config.CacheOnly {
+				return nil, fmt.Errorf("--cache-only: %v", err)
+			}
+			slog.Warn("response cache disabled", "error", err)
+			return nil, nil
+		}
+		dir = filepath.Join(base, "mirror-wget")
+	}
+	if config.Spider {
+		return downloader.OpenCacheReadOnly(dir), nil
+	}
+	return downloader.OpenCache(dir, int64(config.CacheSize))
+}
+
+// resumable загрузки, которые resume ставит в очередь: ещё не обработанные ссылки и неудачные
+// загрузки с ошибкой, которую стоит повторить (RetryPolicy.RetryableFailure), а с retryFailed -
+// все неудачные. Постоянные ошибки вроде 404 иначе повторялись бы при каждом resume
+func resumable(state *journal.State, retry *RetryPolicy, retryFailed bool) []journal.Entry {
+	pending := state.Pending[:len(state.Pending):len(state.Pending)]
+	for _, entry := range state.Failed {
+		if retryFailed || retry.RetryableFailure(entry) {
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+// Restore восстанавливает состояние прерванного задания: скачанные документы
+// повторно не скачиваются, ссылки из pending (см. resumable) скачиваются, а остальные
+// неудачные загрузки считаются посещёнными
+func (e *Engine) Restore(state *journal.State, pending []journal.Entry) {
+	retried := make(map[string]bool, len(pending))
+	for _, entry := range pending {
+		retried[entry.URL] = true
+	}
+	for url := range state.Failed {
+		if !retried[url] {
+			e.visited.Store(url, true)
+		}
+	}
+	for url, entry := range state.Done {
+		e.visited.Store(url, true)
+		if entry.Path == "" {
+			continue
+		}
+
+		normURL, err := normalizer.NewNormalizedURL(url)
+		if err != nil {
+			continue
+		}
+		e.downloadMap.Store(url, entry.Path)
+		for _, redirect := range entry.Redirects {
+			e.downloadMap.Store(redirect, entry.Path)
+		}
+		if finalURL, err := normalizer.NewNormalizedURL(entry.FinalURL); entry.FinalURL != "" && err == nil {
+			e.redirects.Arrived(queue.Item{URL: normURL, Depth: entry.Depth}, finalURL)
+			e.downloadMap.Store(entry.FinalURL, entry.Path)
+			normURL = finalURL
+		}
+		if entry.NotModified || entry.Encoding != "" {
+			continue
+		}
+		e.storageQueue.Push(queue.Item{URL: normURL, Depth: entry.Depth, Requisite: entry.Requisite})
+	}
 
-	log.Printf("Recursion level is %d\n", config.Level)
-	engine := NewEngine(normURL, robotsTxt, runtime.GOMAXPROCS(0)-1, config.Level)
-	return engine.Start()
+	for _, entry := range pending {
+		normURL, err := normalizer.NewNormalizedURL(entry.URL)
+		if err != nil {
+			continue
+		}
+		e.queue.Push(queue.Item{URL: normURL, Depth: entry.Depth, Requisite: entry.Requisite})
+		atomic.AddInt32(&e.activeTasks, 1)
+	}
+	slog.Info("restored job", "done", len(state.Done), "pending", len(pending), "failed", len(state.Failed))
 }
 
 // Start запускает воркеры и диспатчеры
@@ -71,50 +510,112 @@ func (e *Engine) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	jobs := make(chan queue.Item, 100)
-	item := queue.Item{
-		URL:   e.baseURL,
-		Depth: 0,
+	// вход выполняется до обхода: без сессии закрытые страницы недоступны
+	if err := e.session.Login(ctx); err != nil {
+		e.progress.Close()
+		slog.Error("login failed", "error", err)
+		e.summary.Add(e.config.Login.URL, Classify(err), err)
+		e.summary.Print(os.Stderr)
+		return e.summary.Err()
 	}
-	jobs <- item
-	atomic.AddInt32(&e.activeTasks, 1)
 
-	storageQueue := queue.NewQueue()
+	// без буфера: dispatcher выбирает следующую ссылку, только когда освободился воркер,
+	// и ограничение на хост не обходится накопленными в канале задачами
+	jobs := make(chan queue.Item)
+	for _, seed := range e.seeds {
+		e.queue.Push(queue.Item{
+			URL:     seed,
+			Depth:   0,
+			Referer: e.config.Referer,
+		})
+		e.journal.Queued(seed.String(), 0, false)
+		atomic.AddInt32(&e.activeTasks, 1)
+	}
+	// активные задачи - это ожидающие в очереди, отложенные и уже выданные воркерам
+	e.progress.SetPending(func() int {
+		return int(atomic.LoadInt32(&e.activeTasks)) - e.progress.Active()
+	})
 
+	opts := WorkerOptions{
+		Queue:        e.queue,
+		StorageQueue: e.storageQueue,
+		DownloadMap:  e.downloadMap,
+		Fetcher:      e.fetcher,
+		Scope:        e.scope,
+		Layout:       e.layout,
+		Journal:      e.journal,
+		Hosts:        e.hosts,
+		Spider:       e.spider,
+		Progress:     e.progress,
+		Summary:      e.summary,
+		Retry:        e.retry,
+		Previous:     e.previous,
+		Redirects:    e.redirects,
+		Session:      e.session,
+		MaxFileSize:  int64(e.config.MaxFileSize),
+		Resume:       e.config.Continue,
+		KeepEncoded:  e.config.KeepEncoded,
+	}
 	for n := 0; n < e.numWorkers; n++ {
 		e.wg.Add(1)
-		w := NewWorker(e.baseURL, e.wg, &e.activeTasks, e.queue, storageQueue, e.downloadMap)
+		w := NewWorker(e.wg, &e.activeTasks, opts)
 		go w.Worker(ctx, n, jobs)
 	}
 
 	e.wg.Add(1)
-	go e.dispatcher(ctx, e.queue, jobs, cancel)
+	go e.crawlDispatcher(ctx, jobs, cancel)
 
-	log.Println("Ждем завершения worker го рутин")
 	e.wg.Wait()
+	e.progress.Close()
 
-	jobs = make(chan queue.Item, 100)
-	jobs <- item
-	atomic.AddInt32(&e.activeTasks, 1)
+	if e.spider != nil {
+		if err := e.spider.Print(os.Stdout); err != nil {
+			return err
+		}
+	} else if e.config.ConvertLinks {
+		e.convertLinks(e.storageQueue)
+	}
 
-	for n := 0; n < e.numWorkers; n++ {
+	if e.config.SaveCookies != "" {
+		if err := e.cookies.Save(e.config.SaveCookies, e.config.KeepSession); err != nil {
+			return fmt.Errorf("save cookies: %v", err)
+		}
+		slog.Debug("saved cookies", "file", e.config.SaveCookies)
+	}
+
+	// адреса цепочки редиректов ведут к одному файлу, поэтому считаются файлы, а не адреса
+	saved := make(map[interface{}]bool)
+	e.downloadMap.Range(func(key, value interface{}) bool {
+		saved[value] = true
+		return true
+	})
+	slog.Info("job finished", "saved", len(saved), "failed", e.summary.Len())
+
+	if e.summary.Len() > 0 {
+		e.summary.Print(os.Stderr)
+	}
+	return e.summary.Err()
+}
+
+// convertLinks запускает StorageWorker'ы, переписывающие ссылки в скачанных документах (-k)
+func (e *Engine) convertLinks(storageQueue queue.Queue) {
+	// контекст обхода уже отменён dispatcher'ом, поэтому для переписывания нужен свой
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := make(chan queue.Item, 100)
+	atomic.StoreInt32(&e.activeTasks, int32(storageQueue.Len()))
+
+	for n := 0; n < e.numStorage; n++ {
 		e.wg.Add(1)
-		w := NewStorageWorker(e.baseURL, e.wg, storageQueue, &e.activeTasks, e.downloadMap)
+		w := NewStorageWorker(e.wg, storageQueue, &e.activeTasks, e.downloadMap, e.journal, e.summary)
 		go w.Storage(ctx, n, jobs)
 	}
 
 	e.wg.Add(1)
 	go e.dispatcher(ctx, storageQueue, jobs, cancel)
 
-	log.Println("Ждем завершения storage го рутин")
 	e.wg.Wait()
-
-	e.downloadMap.Range(func(key, value interface{}) bool {
-		fmt.Println(key)
-		return true
-	})
-
-	return nil
 }
 
 // dispatcher управляет потоком задач для воркеров
@@ -128,19 +629,6 @@ func (e *Engine) dispatcher(ctx context.Context, itemsQueue queue.Queue, jobs ch
 			return
 		default:
 			if item, ok := itemsQueue.Pop(); ok {
-				if e.maxDepth >= 0 && item.Depth > e.maxDepth {
-					atomic.AddInt32(&e.activeTasks, -1)
-					continue
-				}
-				if _, visited := e.visited.LoadOrStore(item.URL.String(), true); visited {
-					atomic.AddInt32(&e.activeTasks, -1)
-					continue
-				}
-				if e.robotsTxt != nil && !e.robotsTxt.Allowed(item.URL.URL) {
-					atomic.AddInt32(&e.activeTasks, -1)
-					continue
-				}
-
 				select {
 				case jobs <- item:
 				case <-ctx.Done():
@@ -148,12 +636,12 @@ func (e *Engine) dispatcher(ctx context.Context, itemsQueue queue.Queue, jobs ch
 				}
 			} else {
 				// если очередь пуста, ждем SleepDuration
-				log.Printf("Queue is empty, spleeping for: %d millisecond\n", SleepDuration.Milliseconds())
+				logging.Trace("queue is empty", "sleep", SleepDuration)
 				time.Sleep(SleepDuration)
 
 				// проверяем, есть ли еще активные задачи
 				if atomic.LoadInt32(&e.activeTasks) == 0 {
-					log.Println("Активных задач нет")
+					logging.Trace("no active tasks")
 					cancel()
 					return
 				}
@@ -161,3 +649,159 @@ func (e *Engine) dispatcher(ctx context.Context, itemsQueue queue.Queue, jobs ch
 		}
 	}
 }
+
+// crawlDispatcher управляет потоком задач обхода: фильтрует ссылки, соблюдает --max-per-host
+// и паузы между запросами к хосту (-w, --host-rate). Ссылки на занятые хосты откладываются,
+// пока хост не освободится, чтобы медленный хост не занимал всех воркеров; повторы загрузок - до истечения паузы;
+// ссылки, robots.txt хоста которых ещё загружается, - до окончания загрузки
+func (e *Engine) crawlDispatcher(ctx context.Context, jobs chan<- queue.Item, cancel context.CancelFunc) {
+	defer e.wg.Done()
+	defer close(jobs)
+
+	var deferred, waiting []queue.Item
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		item, ok := e.nextDeferred(&deferred)
+		if !ok {
+			item, ok = e.nextRobotsReady(ctx, &waiting)
+			if !ok {
+				item, ok = e.queue.Pop()
+			}
+			if ok {
+				if item.Attempt == 0 && !e.robotsTxt.Ready(ctx, item.URL.URL) {
+					// robots.txt хоста ещё загружается: ссылка ждёт, не задерживая ссылки на другие хосты
+					waiting = append(waiting, item)
+					continue
+				}
+				if admitted, reason := e.admit(ctx, item); !admitted {
+					e.skip(item, reason)
+					continue
+				}
+				if time.Now().Before(item.NotBefore) || !e.acquire(item.URL.URL.Host) {
+					deferred = append(deferred, item)
+					continue
+				}
+			}
+		}
+
+		if !ok {
+			// если очередь пуста, ждем SleepDuration или освобождения хоста
+			logging.Trace("queue is empty", "deferred", len(deferred), "sleep", SleepDuration)
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.hosts.Released():
+			case <-time.After(SleepDuration):
+			}
+
+			// проверяем, есть ли еще активные задачи
+			if atomic.LoadInt32(&e.activeTasks) == 0 {
+				logging.Trace("no active tasks")
+				cancel()
+				return
+			}
+			continue
+		}
+
+		select {
+		case jobs <- item:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// nextDeferred возвращает первую отложенную ссылку, пауза которой истекла и хост которой свободен
+func (e *Engine) nextDeferred(deferred *[]queue.Item) (queue.Item, bool) {
+	now := time.Now()
+	for i, item := range *deferred {
+		if now.Before(item.NotBefore) {
+			continue
+		}
+		if e.acquire(item.URL.URL.Host) {
+			*deferred = append((*deferred)[:i], (*deferred)[i+1:]...)
+			return item, true
+		}
+	}
+	return queue.Item{}, false
+}
+
+// nextRobotsReady возвращает первую ждущую ссылку, robots.txt хоста которой уже загружен
+func (e *Engine) nextRobotsReady(ctx context.Context, waiting *[]queue.Item) (queue.Item, bool) {
+	for i, item := range *waiting {
+		if e.robotsTxt.Ready(ctx, item.URL.URL) {
+			*waiting = append((*waiting)[:i], (*waiting)[i+1:]...)
+			return item, true
+		}
+	}
+	return queue.Item{}, false
+}
+
+// admit проверяет, нужно ли скачивать элемент очереди. reason - причина отказа для журнала;
+// для уже посещённых ссылок она пустая
+func (e *Engine) admit(ctx context.Context, item queue.Item) (ok bool, reason string) {
+	// повтор уже прошёл проверки при первой попытке, а ссылка отмечена посещённой
+	if item.Attempt > 0 {
+		return true, ""
+	}
+	// ресурсы страницы при -p скачиваются независимо от глубины, как в wget
+	if !(item.Requisite && e.config.PageRequisites) && e.maxDepth >= 0 && item.Depth > e.maxDepth {
+		return false, "depth limit"
+	}
+	if _, visited := e.visited.LoadOrStore(item.URL.String(), true); visited {
+		return false, ""
+	}
+	if !e.robotsTxt.Allowed(ctx, item.URL.URL) {
+		return false, reasonRobots
+	}
+	// отклонённые -A/-R документы всё равно скачиваются ради ссылок, но не сохраняются
+	if !e.scope.Accepted(item.URL.URL) && !mayContainLinks(item.URL) {
+		return false, "rejected by -A/-R"
+	}
+	return true, ""
+}
+
+// skip отбрасывает элемент очереди, записывая причину в журнал
+func (e *Engine) skip(item queue.Item, reason string) {
+	if reason != "" && e.spider != nil {
+		e.spider.Skipped(item, reason)
+	}
+	// стартовый URL, запрещённый robots.txt, - ошибка задания, а не просто отброшенная ссылка
+	if reason == reasonRobots && item.Depth == 0 {
+		e.summary.Add(item.URL.String(), CategoryRobots, errors.New(reason))
+	}
+	if reason != "" {
+		e.progress.Skipped()
+		slog.Debug("skipped", logging.KeyURL, item.URL.String(), logging.KeyDepth, item.Depth, "reason", reason)
+		e.journal.Record(journal.Entry{
+			Event: journal.EventSkipped,
+			URL:   item.URL.String(),
+			Depth: item.Depth,
+			Error: reason,
+		})
+	}
+	atomic.AddInt32(&e.activeTasks, -1)
+}
+
+// acquire занимает слот хоста, если к нему сейчас можно отправить запрос
+func (e *Engine) acquire(host string) bool {
+	if !e.pacer.Ready(host) || !e.hosts.TryAcquire(host) {
+		return false
+	}
+	e.pacer.Take(host)
+	return true
+}
+
+// mayContainLinks может ли документ по URL быть HTML страницей
+func mayContainLinks(u *normalizer.NormalizedURL) bool {
+	switch filepath.Ext(u.URL.Path) {
+	case "", ".html", ".htm":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"mirror-wget/internal/cli"
+	"mirror-wget/internal/downloader"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// _memorySite сайт в памяти: адрес - содержимое документа
+type _memorySite map[string]string
+
+// Fetch реализует downloader.Fetcher
+func (s _memorySite) Fetch(ctx context.Context, req *downloader.Request) (*downloader.Response, error) {
+	body, ok := s[req.URL]
+	if !ok {
+		return nil, &downloader.StatusError{Code: http.StatusNotFound}
+	}
+	contentType := "application/octet-stream"
+	switch {
+	case strings.HasSuffix(req.URL, "/"), strings.HasSuffix(req.URL, ".html"):
+		contentType = "text/html"
+	case strings.HasSuffix(req.URL, ".css"):
+		contentType = "text/css"
+	}
+	return &downloader.Response{
+		Status:        http.StatusOK,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentType:   contentType,
+		ContentLength: int64(len(body)),
+		Header:        http.Header{"Content-Type": {contentType}},
+		URL:           req.URL,
+	}, nil
+}
+
+// TestEngineMemorySite тест обхода сайта в памяти через подменённый Fetcher
+func TestEngineMemorySite(t *testing.T) {
+	site := _memorySite{
+		"http://site.test/robots.txt":          "User-agent: *\nDisallow: /private/\n",
+		"http://site.test/":                    `<link rel="stylesheet" href="style.css"><a href="docs/page.html">docs</a><a href="private/secret.html">secret</a>`,
+		"http://site.test/style.css":           `body { background: url(img/bg.png) }`,
+		"http://site.test/img/bg.png":          "png",
+		"http://site.test/docs/page.html":      `<a href="../">home</a>`,
+		"http://site.test/private/secret.html": "secret",
+	}
+
+	var mu sync.Mutex
+	var requested []string
+	record := func(next downloader.Fetcher) downloader.Fetcher {
+		return downloader.FetcherFunc(func(ctx context.Context, req *downloader.Request) (*downloader.Response, error) {
+			mu.Lock()
+			requested = append(requested, req.URL)
+			mu.Unlock()
+			return next.Fetch(ctx, req)
+		})
+	}
+	// сайт в памяти заменяет HTTP клиент целиком
+	memory := func(downloader.Fetcher) downloader.Fetcher { return site }
+
+	root := t.TempDir()
+	config, err := cli.NewConfig([]string{"-r", "-P", root, "--no-netrc", "--no-cache", "http://site.test/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Handle(config, nil, record, memory); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"index.html", "style.css", "img/bg.png", "docs/page.html"} {
+		if _, err := os.Stat(filepath.Join(root, "site.test", path)); err != nil {
+			t.Errorf("expected %s to be saved: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "site.test", "private")); !os.IsNotExist(err) {
+		t.Errorf("expected page disallowed by robots.txt not to be saved, got %v", err)
+	}
+	if !slices.Contains(requested, "http://site.test/robots.txt") {
+		t.Errorf("expected robots.txt to be requested through the fetcher, got %v", requested)
+	}
+	if slices.Contains(requested, "http://site.test/private/secret.html") {
+		t.Error("expected disallowed page not to be requested")
+	}
+}
+
+// TestResumeCredentials тест resume задания, запущенного с учётными данными, которые не сохраняются в журнале
+func TestResumeCredentials(t *testing.T) {
+	site := _memorySite{"http://site.test/": "home"}
+	memory := func(downloader.Fetcher) downloader.Fetcher { return site }
+
+	root := t.TempDir()
+	config, err := cli.NewConfig([]string{"-P", root, "--no-netrc", "--no-cache", "--user", "alice", "--password", "s3cret", "--bearer-token", "t0ken", "http://site.test/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Handle(config, nil, memory); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Resume(root, ResumeOptions{Secrets: cli.Secrets{Password: "s3cret"}}, nil, memory)
+	if err == nil || !strings.Contains(err.Error(), "--bearer-token") {
+		t.Errorf("expected error about missing --bearer-token, got %v", err)
+	}
+	if err := Resume(root, ResumeOptions{Secrets: cli.Secrets{Password: "s3cret", BearerToken: "t0ken"}}, nil, memory); err != nil {
+		t.Errorf("expected resume with all credentials to succeed, got %v", err)
+	}
+}
+
+// TestResumeFailed тест: resume повторяет загрузки с ошибками из --retry-on, а постоянные
+// ошибки (404) - только с RetryFailed
+func TestResumeFailed(t *testing.T) {
+	site := _memorySite{"http://site.test/": `<a href="missing.html">missing</a><a href="busy.html">busy</a>`}
+	var mu sync.Mutex
+	var requested []string
+	busy := true
+	memory := func(downloader.Fetcher) downloader.Fetcher {
+		return downloader.FetcherFunc(func(ctx context.Context, req *downloader.Request) (*downloader.Response, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			requested = append(requested, req.URL)
+			if req.URL == "http://site.test/busy.html" && busy {
+				busy = false
+				return nil, &downloader.StatusError{Code: http.StatusServiceUnavailable}
+			}
+			return site.Fetch(ctx, req)
+		})
+	}
+	site["http://site.test/busy.html"] = "busy"
+
+	root := t.TempDir()
+	config, err := cli.NewConfig([]string{"-r", "-t", "1", "-P", root, "--no-netrc", "--no-cache", "-e", "robots=off", "http://site.test/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Handle(config, nil, memory); err == nil {
+		t.Fatal("expected failed downloads")
+	}
+
+	tests := []struct {
+		name      string
+		opts      ResumeOptions
+		requested []string
+	}{
+		{"retryable", ResumeOptions{}, []string{"http://site.test/busy.html"}},
+		{"complete", ResumeOptions{}, nil},
+		{"retry failed", ResumeOptions{RetryFailed: true}, []string{"http://site.test/missing.html"}},
+	}
+	for _, tt := range tests {
+		requested = nil
+		Resume(root, tt.opts, nil, memory)
+		if !slices.Equal(requested, tt.requested) {
+			t.Errorf("%s: expected requests %v, got %v", tt.name, tt.requested, requested)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import "sync"
+
+// HostLimiter ограничивает число одновременных загрузок с одного хоста (--max-per-host)
+type HostLimiter struct {
+	max      int
+	mu       sync.Mutex
+	active   map[string]int
+	released chan struct{}
+}
+
+// NewHostLimiter инициализирует HostLimiter; max <= 0 - без ограничения
+func NewHostLimiter(max int) *HostLimiter {
+	return &HostLimiter{
+		max:      max,
+		active:   make(map[string]int),
+		released: make(chan struct{}, 1),
+	}
+}
+
+// TryAcquire занимает слот хоста, если он свободен
+func (l *HostLimiter) TryAcquire(host string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.active[host] >= l.max {
+		return false
+	}
+	l.active[host]++
+	return true
+}
+
+// Release освобождает слот хоста и будит ожидающий dispatcher
+func (l *HostLimiter) Release(host string) {
+	l.mu.Lock()
+	if l.active[host] <= 1 {
+		delete(l.active, host)
+	} else {
+		l.active[host]--
+	}
+	l.mu.Unlock()
+
+	select {
+	case l.released <- struct{}{}:
+	default:
+	}
+}
+
+// Released канал, в который приходит сигнал после освобождения слота
+func (l *HostLimiter) Released() <-chan struct{} {
+	return l.released
+}
@@ -0,0 +1,35 @@
+package engine
+
+import "testing"
+
+// TestHostLimiter тест ограничения загрузок с одного хоста
+func TestHostLimiter(t *testing.T) {
+	l := NewHostLimiter(2)
+
+	if !l.TryAcquire("a.example.com") || !l.TryAcquire("a.example.com") {
+		t.Fatal("expected two slots for a.example.com")
+	}
+	if l.TryAcquire("a.example.com") {
+		t.Error("expected third slot for a.example.com to be refused")
+	}
+	if !l.TryAcquire("b.example.com") {
+		t.Error("expected slot for b.example.com")
+	}
+
+	l.Release("a.example.com")
+	select {
+	case <-l.Released():
+	default:
+		t.Error("expected release signal")
+	}
+	if !l.TryAcquire("a.example.com") {
+		t.Error("expected released slot for a.example.com")
+	}
+
+	unlimited := NewHostLimiter(0)
+	for n := 0; n < 100; n++ {
+		if !unlimited.TryAcquire("a.example.com") {
+			t.Fatalf("expected unlimited slots, refused at %d", n)
+		}
+	}
+}
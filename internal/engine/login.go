@@ -0,0 +1,208 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mirror-wget/internal/cli"
+	"mirror-wget/internal/downloader"
+	"mirror-wget/internal/logging"
+	"mirror-wget/internal/parser"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// errSessionExpired документ перенаправил на страницу входа: сессия истекла
+var errSessionExpired = errors.New("session expired: redirected to the login page")
+
+// LoginError вход через форму не удался
+type LoginError struct {
+	URL    string
+	Reason string
+}
+
+// Error реализует error
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("login at %s failed: %s", e.URL, e.Reason)
+}
+
+// Session вход через форму перед обходом (login в файле задания). Cookies сессии попадают
+// в общее хранилище клиента; когда документ перенаправляет на страницу входа, вход повторяется.
+// Методы безопасны для nil получателя - тогда вход не выполняется
+type Session struct {
+	config     cli.Login
+	password   string
+	page       *url.URL
+	fetcher    downloader.Fetcher
+	mu         sync.Mutex
+	generation int // сколько раз выполнен вход
+}
+
+// NewSession инициализирует Session; nil, если вход не задан
+func NewSession(login *cli.Login, password string, fetcher downloader.Fetcher) (*Session, error) {
+	if login == nil {
+		return nil, nil
+	}
+	page, err := url.Parse(login.URL)
+	if err != nil {
+		return nil, fmt.Errorf("login: %v", err)
+	}
+	return &Session{config: *login, password: password, page: page, fetcher: fetcher}, nil
+}
+
+// Login выполняет вход
+func (s *Session) Login(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signIn(ctx)
+}
+
+// Generation номер текущего входа; его передают в Refresh
+func (s *Session) Generation() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+// Refresh повторяет вход после истечения сессии. Если с входа generation вход уже
+// повторил другой воркер, повторно не входит
+func (s *Session) Refresh(ctx context.Context, generation int) error {
+	if s == nil {
+		return errSessionExpired
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation != generation {
+		return nil
+	}
+	slog.Info("session expired, logging in again", logging.KeyURL, s.page.String())
+	return s.signIn(ctx)
+}
+
+// IsLoginPage ведёт ли u на страницу входа
+func (s *Session) IsLoginPage(u *url.URL) bool {
+	return s != nil && strings.EqualFold(u.Host, s.page.Host) && u.Path == s.page.Path
+}
+
+// Expired привёл ли запрос документа на страницу входа
+func (s *Session) Expired(resp *downloader.Response) bool {
+	if s == nil || len(resp.Redirects) == 0 {
+		return false
+	}
+	u, err := url.Parse(resp.URL)
+	return err == nil && s.IsLoginPage(u)
+}
+
+// signIn загружает страницу входа, заполняет форму и отправляет её
+func (s *Session) signIn(ctx context.Context) error {
+	// токен CSRF одноразовый, поэтому страница входа не берётся из кеша
+	resp, err := s.fetcher.Fetch(ctx, &downloader.Request{
+		URL:    s.page.String(),
+		Header: http.Header{"Cache-Control": {"no-cache"}},
+	})
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	forms, err := parser.ParseForms(io.LimitReader(resp.Body, MaxParseSize))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	form := s.form(forms)
+	if form == nil {
+		return &LoginError{URL: s.page.String(), Reason: "no login form on the page"}
+	}
+
+	base, err := url.Parse(resp.URL)
+	if err != nil {
+		base = s.page
+	}
+	action, err := base.Parse(form.Action)
+	if err != nil {
+		return &LoginError{URL: s.page.String(), Reason: fmt.Sprintf("invalid form action %q", form.Action)}
+	}
+	values := make(url.Values)
+	for _, field := range form.Fields {
+		values.Add(field.Name, field.Value)
+	}
+	for name, value := range s.config.Fields {
+		values.Set(name, value)
+	}
+	passwordField := s.config.PasswordField
+	if passwordField == "" {
+		passwordField = form.Password
+	}
+	if passwordField != "" {
+		values.Set(passwordField, s.password)
+	}
+
+	req := &downloader.Request{URL: action.String(), Form: values}
+	if form.Method == "get" {
+		action.RawQuery = values.Encode()
+		req = &downloader.Request{URL: action.String()}
+	}
+	resp, err = s.fetcher.Fetch(downloader.WithReferer(ctx, base.String()), req)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxParseSize))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if err := s.check(resp.URL, body); err != nil {
+		return err
+	}
+
+	s.generation++
+	slog.Info("logged in", logging.KeyURL, s.page.String(), "landed", resp.URL)
+	return nil
+}
+
+// form форма входа: заданная id или name, иначе первая с полем пароля, иначе единственная
+func (s *Session) form(forms []parser.Form) *parser.Form {
+	for i := range forms {
+		if s.config.Form != "" && (forms[i].ID == s.config.Form || forms[i].Name == s.config.Form) {
+			return &forms[i]
+		}
+	}
+	if s.config.Form != "" {
+		return nil
+	}
+	for i := range forms {
+		if forms[i].Password != "" {
+			return &forms[i]
+		}
+	}
+	if len(forms) == 1 {
+		return &forms[0]
+	}
+	return nil
+}
+
+// check проверяет по адресу и содержимому страницы после отправки формы, что вход выполнен
+func (s *Session) check(landed string, body []byte) error {
+	if s.config.SuccessURL != "" && !strings.Contains(landed, s.config.SuccessURL) {
+		return &LoginError{URL: s.page.String(), Reason: fmt.Sprintf("landed on %s, expected URL containing %q", landed, s.config.SuccessURL)}
+	}
+	if s.config.SuccessText != "" && !bytes.Contains(body, []byte(s.config.SuccessText)) {
+		return &LoginError{URL: s.page.String(), Reason: fmt.Sprintf("page %s does not contain %q", landed, s.config.SuccessText)}
+	}
+	if s.config.SuccessURL == "" && s.config.SuccessText == "" {
+		if u, err := url.Parse(landed); err == nil && s.IsLoginPage(u) {
+			return &LoginError{URL: s.page.String(), Reason: "returned to the login page"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"fmt"
+	"mirror-wget/internal/cli"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// _portal сайт, страницы которого доступны только после входа через форму с токеном CSRF.
+// После expireAfter закрытых страниц все сессии истекают
+type _portal struct {
+	mu          sync.Mutex
+	tokens      map[string]bool
+	sessions    map[string]bool
+	logins      int
+	served      int
+	expireAfter int
+}
+
+// ServeHTTP реализует http.Handler
+func (p *_portal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/login":
+		token := fmt.Sprintf("token-%d", len(p.tokens))
+		p.tokens[token] = true
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<form method="post" action="/session"><input type="hidden" name="csrf" value="%s">
+			<input name="user"><input type="password" name="pass"><input type="submit" value="Sign in"></form>`, token)
+		return
+	case "/session":
+		if r.Method != http.MethodPost || !p.tokens[r.PostFormValue("csrf")] ||
+			r.PostFormValue("user") != "bot" || r.PostFormValue("pass") != "secret" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		delete(p.tokens, r.PostFormValue("csrf"))
+		p.logins++
+		sid := fmt.Sprintf("session-%d", p.logins)
+		p.sessions[sid] = true
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: sid, Path: "/"})
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+		return
+	}
+
+	if cookie, err := r.Cookie("sid"); err != nil || !p.sessions[cookie.Value] {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	p.served++
+	if p.served == p.expireAfter {
+		clear(p.sessions)
+	}
+	w.Header().Set("Content-Type", "text/html")
+	switch r.URL.Path {
+	case "/home":
+		fmt.Fprint(w, `<p>Sign out</p><a href="a.html">a</a>`)
+	case "/a.html":
+		fmt.Fprint(w, `<p>page a</p><a href="b.html">b</a>`)
+	case "/b.html":
+		fmt.Fprint(w, `<p>page b</p>`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TestEngineLogin тест входа через форму и повторного входа после истечения сессии
+func TestEngineLogin(t *testing.T) {
+	portal := &_portal{tokens: make(map[string]bool), sessions: make(map[string]bool), expireAfter: 2}
+	srv := httptest.NewServer(portal)
+	defer srv.Close()
+
+	root := t.TempDir()
+	job := filepath.Join(t.TempDir(), "job.toml")
+	os.WriteFile(job, []byte(fmt.Sprintf(`
+		urls = [%q]
+		[login]
+		url = %q
+		password_env = "TEST_PORTAL_PASSWORD"
+		success_text = "Sign out"
+		[login.fields]
+		user = "bot"
+	`, srv.URL+"/home", srv.URL+"/login")), 0o644)
+	t.Setenv("TEST_PORTAL_PASSWORD", "secret")
+
+	config, err := cli.NewConfig([]string{"--config", job, "-r", "-nH", "-P", root, "--no-netrc", "--no-cache", "--workers", "1", "-e", "robots=off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Handle(config, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expect := range map[string]string{"home/index.html": "Sign out", "a.html": "page a", "b.html": "page b"} {
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil || !strings.Contains(string(data), expect) {
+			t.Errorf("expected %s with %q, got %q, %v", path, expect, data, err)
+		}
+	}
+	if portal.logins != 2 {
+		t.Errorf("expected login and one relogin after the session expired, got %d logins", portal.logins)
+	}
+	journal, _ := os.ReadFile(filepath.Join(root, ".mirror-wget", "journal.jsonl"))
+	if strings.Contains(string(journal), "secret") {
+		t.Error("expected password not to be written to the journal")
+	}
+
+	// неверный пароль - ошибка входа до начала обхода
+	t.Setenv("TEST_PORTAL_PASSWORD", "wrong")
+	config, _ = cli.NewConfig([]string{"--config", job, "-P", t.TempDir(), "--no-netrc", "--no-cache"})
+	if err := Handle(config, nil); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("expected login failure, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Pacer распределяет запросы к хостам во времени: выдерживает паузу -w (--random-wait)
+// между запросами к одному хосту и ограничивает частоту запросов к хосту (--host-rate).
+// Один Pacer общий для всех воркеров, его проверяет dispatcher перед выдачей ссылки.
+// Методы безопасны для nil получателя - тогда запросы не ограничиваются
+type Pacer struct {
+	wait   time.Duration
+	random bool
+	rate   float64 // запросов в секунду к одному хосту, 0 - без ограничения
+	burst  float64
+	mu     sync.Mutex
+	hosts  map[string]*hostPace
+}
+
+// hostPace состояние одного хоста
+type hostPace struct {
+	next    time.Time // раньше запрос к хосту не выдаётся (-w)
+	tokens  float64   // token bucket --host-rate
+	updated time.Time
+}
+
+// NewPacer инициализирует Pacer; nil, если ограничений нет
+func NewPacer(wait time.Duration, random bool, rate float64, burst int) *Pacer {
+	if wait <= 0 && rate <= 0 {
+		return nil
+	}
+	return &Pacer{
+		wait:   wait,
+		random: random,
+		rate:   rate,
+		burst:  float64(max(1, burst)),
+		hosts:  make(map[string]*hostPace),
+	}
+}
+
+// Ready можно ли сейчас отправить запрос к хосту
+func (p *Pacer) Ready(host string) bool {
+	if p == nil {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := p.hosts[host]
+	if h == nil {
+		return true
+	}
+	now := time.Now()
+	if now.Before(h.next) {
+		return false
+	}
+	return p.rate <= 0 || p.refill(h, now) >= 1
+}
+
+// Take отмечает отправку запроса к хосту
+func (p *Pacer) Take(host string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	h := p.hosts[host]
+	if h == nil {
+		h = &hostPace{tokens: p.burst, updated: now}
+		p.hosts[host] = h
+	}
+	if p.rate > 0 {
+		p.refill(h, now)
+		h.tokens--
+	}
+	wait := p.wait
+	if p.random {
+		wait = time.Duration((0.5 + rand.Float64()) * float64(wait))
+	}
+	h.next = now.Add(wait)
+}
+
+// refill пополняет token bucket хоста к моменту now и возвращает число токенов
+func (p *Pacer) refill(h *hostPace, now time.Time) float64 {
+	h.tokens = min(p.burst, h.tokens+now.Sub(h.updated).Seconds()*p.rate)
+	h.updated = now
+	return h.tokens
+}
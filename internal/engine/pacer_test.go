@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPacer тест пауз между запросами к одному хосту
+func TestPacer(t *testing.T) {
+	tests := []struct {
+		name  string
+		pacer *Pacer
+		ready []bool // Ready перед каждым из запросов, отправленных подряд
+	}{
+		{"no limits", NewPacer(0, false, 0, 0), []bool{true, true, true}},
+		{"wait", NewPacer(time.Hour, false, 0, 0), []bool{true, false, false}},
+		{"random wait", NewPacer(time.Hour, true, 0, 0), []bool{true, false}},
+		{"host rate", NewPacer(0, false, 0.001, 1), []bool{true, false}},
+		{"host burst", NewPacer(0, false, 0.001, 3), []bool{true, true, true, false}},
+	}
+	for _, test := range tests {
+		for n, expected := range test.ready {
+			if ready := test.pacer.Ready("a.example.com"); ready != expected {
+				t.Errorf("%s: request %d: expected ready %v, got %v", test.name, n, expected, ready)
+			}
+			if expected {
+				test.pacer.Take("a.example.com")
+			}
+		}
+		// паузы считаются для каждого хоста отдельно
+		if !test.pacer.Ready("b.example.com") {
+			t.Errorf("%s: expected other host to be ready", test.name)
+		}
+	}
+
+	p := NewPacer(20*time.Millisecond, false, 0, 0)
+	p.Take("a.example.com")
+	time.Sleep(30 * time.Millisecond)
+	if !p.Ready("a.example.com") {
+		t.Error("expected host to be ready after wait")
+	}
+}
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"log/slog"
+	"mirror-wget/internal/logging"
+	"mirror-wget/internal/normalizer"
+	"mirror-wget/internal/queue"
+	"mirror-wget/internal/scope"
+	"net/url"
+	"sync"
+)
+
+// RedirectPolicy решает, следовать ли редиректу, и отмечает адреса, на которые редиректы привели.
+// Методы безопасны для nil получателя - тогда выполняются все редиректы
+type RedirectPolicy struct {
+	scope         *scope.Scope
+	visited       *sync.Map
+	followOffsite bool // --offsite-redirects follow: скачивать документы, на которые редирект ведёт за область обхода
+}
+
+// NewRedirectPolicy инициализирует RedirectPolicy
+func NewRedirectPolicy(scope *scope.Scope, visited *sync.Map, followOffsite bool) *RedirectPolicy {
+	return &RedirectPolicy{scope: scope, visited: visited, followOffsite: followOffsite}
+}
+
+// Allow следовать ли редиректу при загрузке item на адрес to. Редиректы стартовых URL
+// выполняются всегда: сайт часто переезжает на https или на www
+func (p *RedirectPolicy) Allow(item queue.Item, to *url.URL) bool {
+	if p == nil || item.Depth == 0 || p.followOffsite || p.scope.Allowed(to) {
+		return true
+	}
+	slog.Debug("redirect out of scope", logging.KeyURL, item.URL.String(), "to", to.String())
+	return false
+}
+
+// Arrived отмечает адрес после редиректов посещённым, чтобы документ не скачивался повторно.
+// Если редирект стартового URL вывел за область обхода, адрес становится стартовым
+func (p *RedirectPolicy) Arrived(item queue.Item, final *normalizer.NormalizedURL) {
+	if p == nil {
+		return
+	}
+	p.visited.Store(final.String(), true)
+	if item.Depth == 0 && !p.scope.Allowed(final.URL) {
+		slog.Info("start URL redirected, following its site", logging.KeyURL, item.URL.String(), "to", final.String())
+		p.scope.AddSeed(final.URL)
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"mirror-wget/internal/normalizer"
+	"mirror-wget/internal/queue"
+	"mirror-wget/internal/scope"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestProcessItemRedirect тест сохранения документа по адресу после редиректов
+func TestProcessItemRedirect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			http.Redirect(w, r, "/docs/v2/", http.StatusMovedPermanently)
+		case "/docs/v2/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="page.html">page</a>`))
+		case "/old.html":
+			http.Redirect(w, r, "http://other.example.test/new.html", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	seed, _ := normalizer.NewNormalizedURL(srv.URL + "/")
+	sc := scope.NewScope(nil, false, nil, nil)
+	sc.AddSeed(seed.URL)
+	visited := &sync.Map{}
+	w := _newTestWorker(root, WorkerOptions{Scope: sc, Redirects: NewRedirectPolicy(sc, visited, false)})
+
+	docs, _ := normalizer.NewNormalizedURL(srv.URL + "/docs")
+	w.processItem(context.Background(), queue.Item{URL: docs, Depth: 1})
+
+	final := srv.URL + "/docs/v2/"
+	path, _ := w.downloadMap.Load(final)
+	if original, _ := w.downloadMap.Load(docs.String()); path == nil || original != path {
+		t.Fatalf("expected %s and %s mapped to one file, got %v and %v", docs, final, original, path)
+	}
+	if rel, _ := filepath.Rel(root, path.(string)); filepath.ToSlash(rel) != seed.URL.Host+"/docs/v2/index.html" {
+		t.Errorf("unexpected save path %s", rel)
+	}
+	if _, ok := visited.Load(final); !ok {
+		t.Errorf("expected %s to be marked visited", final)
+	}
+	if item, ok := w.queue.Pop(); !ok || item.URL.String() != final+"page.html" {
+		t.Errorf("expected link resolved against %s, got %+v", final, item.URL)
+	}
+	if item, ok := w.storageQueue.Pop(); !ok || item.URL.String() != final {
+		t.Errorf("expected rewrite of %s, got %+v", final, item.URL)
+	}
+
+	// редирект за область обхода не выполняется, документ не сохраняется
+	old, _ := normalizer.NewNormalizedURL(srv.URL + "/old.html")
+	w.processItem(context.Background(), queue.Item{URL: old, Depth: 1})
+	if _, ok := w.downloadMap.Load(old.String()); ok || w.summary.Len() != 0 {
+		t.Errorf("expected offsite redirect to be skipped, failures %d", w.summary.Len())
+	}
+	if _, err := os.Stat(filepath.Join(root, "other.example.test")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing saved for offsite redirect, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"mirror-wget/internal/downloader"
+	"mirror-wget/internal/journal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryNetwork значение --retry-on для сетевых ошибок: обрывов соединения, таймаутов, DNS
+const RetryNetwork = "network"
+
+// baseRetryDelay пауза перед первым повтором; дальше она удваивается
+const baseRetryDelay = time.Second
+
+// maxRetryAfter предел паузы из Retry-After, если --waitretry меньше: сервер не может
+// остановить загрузку на часы одним заголовком
+const maxRetryAfter = 5 * time.Minute
+
+// RetryPolicy правила повтора неудачных загрузок (--tries, --retry-on, --waitretry)
+type RetryPolicy struct {
+	tries    int // всего попыток, 0 - без ограничения
+	codes    map[int]bool
+	classes  map[int]bool // первая цифра кода: 5 для 5xx
+	network  bool
+	maxDelay time.Duration
+}
+
+// NewRetryPolicy инициализирует RetryPolicy. retryOn - коды ответа ("429"), классы ("5xx")
+// и RetryNetwork; maxDelay ограничивает экспоненциальную паузу. Retry-After сервера
+// учитывается, даже если он больше maxDelay, но не больше max(maxDelay, maxRetryAfter)
+func NewRetryPolicy(tries int, retryOn []string, maxDelay time.Duration) (*RetryPolicy, error) {
+	p := &RetryPolicy{
+		tries:    tries,
+		codes:    make(map[int]bool),
+		classes:  make(map[int]bool),
+		maxDelay: maxDelay,
+	}
+
+	for _, token := range retryOn {
+		token = strings.ToLower(strings.TrimSpace(token))
+		switch {
+		case token == RetryNetwork:
+			p.network = true
+		case len(token) == 3 && strings.HasSuffix(token, "xx") && token[0] >= '1' && token[0] <= '5':
+			p.classes[int(token[0]-'0')] = true
+		default:
+			code, err := strconv.Atoi(token)
+			if err != nil || code < 100 || code > 599 {
+				return nil, fmt.Errorf("invalid --retry-on value %q: expected status code, class like 5xx or %s", token, RetryNetwork)
+			}
+			p.codes[code] = true
+		}
+	}
+	return p, nil
+}
+
+// Retry нужно ли повторить загрузку после неудачной попытки attempt (с нуля) и через сколько
+func (p *RetryPolicy) Retry(attempt int, err error) (time.Duration, bool) {
+	if p == nil || (p.tries > 0 && attempt+1 >= p.tries) || !p.retryable(err) {
+		return 0, false
+	}
+
+	delay := p.backoff(attempt)
+	var statusErr *downloader.StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+		delay = statusErr.RetryAfter
+		if ceiling := max(p.maxDelay, maxRetryAfter); delay > ceiling {
+			slog.Warn("Retry-After is too long, waiting less", "retry_after", delay, "delay", ceiling)
+			delay = ceiling
+		}
+	}
+	return delay, true
+}
+
+// retryable подходит ли ошибка под --retry-on
+func (p *RetryPolicy) retryable(err error) bool {
+	var statusErr *downloader.StatusError
+	if errors.As(err, &statusErr) {
+		return p.codes[statusErr.Code] || p.classes[statusErr.Code/100]
+	}
+	return p.network && Classify(err) == CategoryNetwork
+}
+
+// RetryableFailure подходит ли неудачная загрузка из журнала под --retry-on: такие загрузки
+// resume повторяет. Ошибки записи файла тоже повторяются - их причина на этой машине.
+// В журналах без кода и категории ошибки повторяется всё
+func (p *RetryPolicy) RetryableFailure(entry journal.Entry) bool {
+	switch {
+	case p == nil:
+		return false
+	case entry.Status != 0:
+		return p.codes[entry.Status] || p.classes[entry.Status/100]
+	case entry.Category == string(CategoryNetwork):
+		return p.network
+	default:
+		return entry.Category == "" || entry.Category == string(CategoryIO)
+	}
+}
+
+// backoff экспоненциальная пауза со случайным разбросом от половины до полного значения,
+// чтобы воркеры не повторяли запросы к одному хосту одновременно
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.maxDelay
+	if attempt < 30 {
+		delay = min(baseRetryDelay<<attempt, p.maxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"mirror-wget/internal/downloader"
+	"mirror-wget/internal/journal"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestRetryPolicy тест выбора ошибок для повтора и числа попыток
+func TestRetryPolicy(t *testing.T) {
+	p, err := NewRetryPolicy(3, []string{"429", "5xx", "network"}, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reset := fmt.Errorf("download failed: %w", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")})
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		retry   bool
+	}{
+		{name: "too many requests", err: &downloader.StatusError{Code: 429}, retry: true},
+		{name: "bad gateway", err: &downloader.StatusError{Code: 502}, retry: true},
+		{name: "not found", err: &downloader.StatusError{Code: 404}, retry: false},
+		{name: "connection reset", err: reset, retry: true},
+		{name: "protocol error", err: errors.New("malformed HTTP response"), retry: false},
+		{name: "second retry", attempt: 1, err: &downloader.StatusError{Code: 503}, retry: true},
+		{name: "tries exhausted", attempt: 2, err: &downloader.StatusError{Code: 503}, retry: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := p.Retry(tt.attempt, tt.err)
+			if retry != tt.retry {
+				t.Fatalf("expected retry %v, got %v", tt.retry, retry)
+			}
+			if retry && (delay <= 0 || delay > 10*time.Second) {
+				t.Errorf("unexpected delay %v", delay)
+			}
+		})
+	}
+
+	unlimited, _ := NewRetryPolicy(0, []string{"503"}, time.Second)
+	if _, retry := unlimited.Retry(100, &downloader.StatusError{Code: 503}); !retry {
+		t.Error("expected unlimited tries with -t 0")
+	}
+
+	if _, err := NewRetryPolicy(3, []string{"6xx"}, time.Second); err == nil {
+		t.Error("expected error for invalid --retry-on value, got nil")
+	}
+}
+
+// TestRetryPolicyDelay тест экспоненциальной паузы и Retry-After
+func TestRetryPolicyDelay(t *testing.T) {
+	p, _ := NewRetryPolicy(0, []string{"5xx"}, 8*time.Second)
+
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		delay, _ := p.Retry(attempt, &downloader.StatusError{Code: 500})
+		if delay < max/2 || delay > max {
+			t.Errorf("attempt %d: expected delay in [%v, %v], got %v", attempt, max/2, max, delay)
+		}
+	}
+
+	delay, _ := p.Retry(0, &downloader.StatusError{Code: 503, RetryAfter: time.Minute})
+	if delay != time.Minute {
+		t.Errorf("expected Retry-After delay of 1m, got %v", delay)
+	}
+
+	// слишком долгий Retry-After ограничен maxRetryAfter или --waitretry, если он больше
+	delay, _ = p.Retry(0, &downloader.StatusError{Code: 503, RetryAfter: 24 * time.Hour})
+	if delay != maxRetryAfter {
+		t.Errorf("expected Retry-After delay capped at %v, got %v", maxRetryAfter, delay)
+	}
+	long, _ := NewRetryPolicy(0, []string{"5xx"}, time.Hour)
+	delay, _ = long.Retry(0, &downloader.StatusError{Code: 503, RetryAfter: 24 * time.Hour})
+	if delay != time.Hour {
+		t.Errorf("expected Retry-After delay capped at --waitretry 1h, got %v", delay)
+	}
+}
+
+// TestRetryableFailure тест выбора неудачных загрузок, которые повторяет resume
+func TestRetryableFailure(t *testing.T) {
+	p, _ := NewRetryPolicy(3, []string{"429", "5xx", RetryNetwork}, time.Second)
+	tests := []struct {
+		entry journal.Entry
+		retry bool
+	}{
+		{journal.Entry{Status: 503, Category: string(CategoryServer)}, true},
+		{journal.Entry{Status: 429, Category: string(CategoryServer)}, true},
+		{journal.Entry{Status: 404, Category: string(CategoryServer)}, false},
+		{journal.Entry{Status: 410, Category: string(CategoryServer)}, false},
+		{journal.Entry{Status: 401, Category: string(CategoryAuth)}, false},
+		{journal.Entry{Category: string(CategoryNetwork)}, true},
+		{journal.Entry{Category: string(CategoryIO)}, true},
+		{journal.Entry{Category: string(CategoryTLS)}, false},
+		{journal.Entry{}, true}, // журнал без категорий
+	}
+	for _, tt := range tests {
+		if got := p.RetryableFailure(tt.entry); got != tt.retry {
+			t.Errorf("%+v: expected %v, got %v", tt.entry, tt.retry, got)
+		}
+	}
+}
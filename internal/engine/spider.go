@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"mirror-wget/internal/queue"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+)
+
+// SpiderResult результат обработки ссылки в режиме --spider
+type SpiderResult struct {
+	URL         string
+	Depth       int
+	Status      int // код ответа; 0, если запрос не выполнялся или не дошёл до сервера
+	ContentType string
+	Size        int64
+	Note        string // ошибка или причина, по которой ссылка отброшена
+}
+
+// SpiderReport собирает результаты обхода в режиме --spider
+type SpiderReport struct {
+	mu      sync.Mutex
+	results []SpiderResult
+}
+
+// NewSpiderReport инициализирует SpiderReport
+func NewSpiderReport() *SpiderReport {
+	return &SpiderReport{}
+}
+
+// Add добавляет результат обработки ссылки
+func (r *SpiderReport) Add(result SpiderResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+}
+
+// Skipped добавляет ссылку, отброшенную dispatcher'ом
+func (r *SpiderReport) Skipped(item queue.Item, reason string) {
+	r.Add(SpiderResult{URL: item.URL.String(), Depth: item.Depth, Note: reason})
+}
+
+// Results возвращает результаты, упорядоченные по глубине и URL
+func (r *SpiderReport) Results() []SpiderResult {
+	r.mu.Lock()
+	results := append([]SpiderResult(nil), r.results...)
+	r.mu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Depth != results[j].Depth {
+			return results[i].Depth < results[j].Depth
+		}
+		return results[i].URL < results[j].URL
+	})
+	return results
+}
+
+// Print выводит результаты таблицей
+func (r *SpiderReport) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tTYPE\tSIZE\tDEPTH\tURL\tNOTE")
+	for _, result := range r.Results() {
+		status := "-"
+		if result.Status != 0 {
+			status = strconv.Itoa(result.Status)
+		}
+		contentType := result.ContentType
+		if contentType == "" {
+			contentType = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\n",
+			status, contentType, result.Size, result.Depth, result.URL, result.Note)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"mirror-wget/internal/normalizer"
+	"mirror-wget/internal/queue"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSpiderReportPrint тест вывода результатов --spider
+func TestSpiderReportPrint(t *testing.T) {
+	r := NewSpiderReport()
+	r.Add(SpiderResult{URL: "https://example.com/b.css", Depth: 1, Status: 200, ContentType: "text/css", Size: 20})
+	r.Add(SpiderResult{URL: "https://example.com/", Depth: 0, Status: 200, ContentType: "text/html", Size: 100})
+	r.Add(SpiderResult{URL: "https://example.com/a", Depth: 1, Status: 404, Note: "status code 404"})
+	r.Add(SpiderResult{URL: "https://example.com/private/", Depth: 1, Note: "disallowed by robots.txt"})
+
+	var buf bytes.Buffer
+	if err := r.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expect := [][]string{
+		{"STATUS", "TYPE", "SIZE", "DEPTH", "URL", "NOTE"},
+		{"200", "text/html", "100", "0", "https://example.com/"},
+		{"404", "-", "0", "1", "https://example.com/a", "status", "code", "404"},
+		{"200", "text/css", "20", "1", "https://example.com/b.css"},
+		{"-", "-", "0", "1", "https://example.com/private/", "disallowed", "by", "robots.txt"},
+	}
+	if len(lines) != len(expect) {
+		t.Fatalf("expected %d lines, got %q", len(expect), buf.String())
+	}
+	for i, line := range lines {
+		if got := strings.Fields(line); strings.Join(got, " ") != strings.Join(expect[i], " ") {
+			t.Errorf("line %d: expected %v, got %v", i, expect[i], got)
+		}
+	}
+}
+
+// TestSpiderStatus тест: ответы 2xx записываются в результаты --spider как успешные
+// загрузки с кодом ответа сервера, а не как ошибки
+func TestSpiderStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/proxy.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusNonAuthoritativeInfo)
+			w.Write([]byte("<p>copy</p>"))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	report := NewSpiderReport()
+	w := _newTestWorker(t.TempDir(), WorkerOptions{Spider: report})
+	expected := map[string]int{
+		srv.URL + "/proxy.html": http.StatusNonAuthoritativeInfo,
+		srv.URL + "/empty":      http.StatusNoContent,
+		srv.URL + "/page":       http.StatusOK,
+	}
+	for raw := range expected {
+		u, _ := normalizer.NewNormalizedURL(raw)
+		w.processItem(context.Background(), queue.Item{URL: u})
+	}
+
+	results := report.Results()
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %+v", len(expected), results)
+	}
+	for _, r := range results {
+		if r.Status != expected[r.URL] || r.Note != "" {
+			t.Errorf("%s: expected status %d without note, got %d %q", r.URL, expected[r.URL], r.Status, r.Note)
+		}
+	}
+	if n := w.summary.Len(); n != 0 {
+		t.Errorf("expected no failures, got %d", n)
+	}
+}
@@ -2,37 +2,42 @@ package engine
 
 import (
 	"context"
-	"log"
-	"mirror-wget/internal/normalizer"
+	"log/slog"
+	"mirror-wget/internal/journal"
+	"mirror-wget/internal/logging"
 	"mirror-wget/internal/queue"
 	"mirror-wget/internal/storage"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // StorageWorker структура для задач по изменению интернет-ссылок на локальные
 type StorageWorker struct {
-	baseURL     *normalizer.NormalizedURL
 	wg          *sync.WaitGroup
 	queue       queue.Queue
 	activeTasks *int32
 	downloadMap *sync.Map
+	journal     *journal.Journal
+	summary     *Summary
 }
 
 // NewStorageWorker инициализирует StorageWorker
 func NewStorageWorker(
-	baseURL *normalizer.NormalizedURL,
 	wg *sync.WaitGroup,
 	queue queue.Queue,
 	activeTasks *int32,
-	downloadMap *sync.Map) *StorageWorker {
+	downloadMap *sync.Map,
+	journal *journal.Journal,
+	summary *Summary) *StorageWorker {
 	return &StorageWorker{
-		baseURL:     baseURL,
 		wg:          wg,
 		queue:       queue,
 		activeTasks: activeTasks,
 		downloadMap: downloadMap,
+		journal:     journal,
+		summary:     summary,
 	}
 }
 
@@ -40,7 +45,7 @@ func NewStorageWorker(
 func (w *StorageWorker) Storage(ctx context.Context, id int, jobs <-chan queue.Item) {
 	defer w.wg.Done()
 
-	log.Printf("storage worker %d starting\n", id)
+	logging.Trace("storage worker starting", "worker", id)
 
 	for {
 		select {
@@ -60,30 +65,47 @@ func (w *StorageWorker) Storage(ctx context.Context, id int, jobs <-chan queue.I
 
 // processItem обработка задачи
 func (w *StorageWorker) processItem(ctx context.Context, item queue.Item) {
-	log.Printf("Processing %s\n", item.URL.String())
 	fp, ok := w.downloadMap.Load(item.URL.String())
 	if !ok {
 		return
 	}
-	log.Printf("Loaded: %s (filepath: %s)\n", item.URL.String(), fp)
 	var st storage.Rewriter
 
 	// which storage use
 	pathResolver := storage.NewPathResolver(item.URL, w.downloadMap)
 	extension := filepath.Ext(fp.(string))
-	if extension == "html" {
+	if extension == ".html" || extension == ".htm" {
 		st = storage.NewHTMLRewriter(pathResolver)
-	} else if extension == "css" {
-		log.Printf("Loaded: %s (filepath: %s)\n", item.URL.String(), fp)
+	} else if extension == ".css" {
 		st = storage.NewCSSRewriter(pathResolver)
 	} else {
 		return
 	}
 
+	start := time.Now()
 	err := st.Rewrite(ctx, fp.(string))
 	if err != nil {
-		log.Println(err)
+		slog.Warn("rewrite failed", logging.KeyURL, item.URL.String(), logging.KeyStage, "rewrite", "error", err)
+		w.summary.Add(item.URL.String(), CategoryIO, err)
 		return
 	}
-	log.Printf("Rewritten: %s\n", item.URL.String())
+	slog.Debug("rewritten",
+		logging.KeyURL, item.URL.String(),
+		logging.KeyStage, "rewrite",
+		logging.KeyDuration, time.Since(start),
+		"path", fp)
+
+	size, sum, err := journal.HashFile(fp.(string))
+	if err != nil {
+		slog.Warn("hash failed", logging.KeyURL, item.URL.String(), logging.KeyStage, "rewrite", "error", err)
+		w.summary.Add(item.URL.String(), CategoryIO, err)
+		return
+	}
+	w.journal.Record(journal.Entry{
+		Event:  journal.EventRewritten,
+		URL:    item.URL.String(),
+		Path:   fp.(string),
+		Size:   size,
+		SHA256: sum,
+	})
 }
@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"mirror-wget/internal/downloader"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// Category категория ошибки задания
+type Category string
+
+const (
+	CategoryIO       Category = "io"       // не удалось записать файл
+	CategoryNetwork  Category = "network"  // соединение, DNS, таймауты
+	CategoryTLS      Category = "tls"      // проверка сертификата
+	CategoryAuth     Category = "auth"     // 401 и 407
+	CategoryRobots   Category = "robots"   // стартовый URL запрещён robots.txt
+	CategoryProtocol Category = "protocol" // некорректный ответ, слишком много редиректов
+	CategoryServer   Category = "server"   // прочие ответы 4xx и 5xx
+)
+
+// Коды завершения задания, как у wget. Если ошибок несколько, побеждает меньший код
+const (
+	ExitIO       = 3
+	ExitNetwork  = 4
+	ExitTLS      = 5
+	ExitAuth     = 6 // также стартовый URL, запрещённый robots.txt
+	ExitProtocol = 7
+	ExitServer   = 8
+)
+
+// exitCodes код завершения для каждой категории
+var exitCodes = map[Category]int{
+	CategoryIO:       ExitIO,
+	CategoryNetwork:  ExitNetwork,
+	CategoryTLS:      ExitTLS,
+	CategoryAuth:     ExitAuth,
+	CategoryRobots:   ExitAuth,
+	CategoryProtocol: ExitProtocol,
+	CategoryServer:   ExitServer,
+}
+
+// maxExamples сколько URL каждой категории показывать в сводке
+const maxExamples = 3
+
+// Failure ошибка обработки ссылки
+type Failure struct {
+	URL      string
+	Category Category
+	Err      error
+}
+
+// Summary собирает ошибки задания для итоговой сводки и кода завершения
+type Summary struct {
+	mu       sync.Mutex
+	failures []Failure
+}
+
+// NewSummary инициализирует Summary
+func NewSummary() *Summary {
+	return &Summary{}
+}
+
+// Add добавляет ошибку
+func (s *Summary) Add(url string, category Category, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, Failure{URL: url, Category: category, Err: err})
+}
+
+// Len число ошибок
+func (s *Summary) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.failures)
+}
+
+// ExitCode код завершения задания: 0 без ошибок, иначе наименьший код среди категорий
+func (s *Summary) ExitCode() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := 0
+	for _, failure := range s.failures {
+		if c := exitCodes[failure.Category]; code == 0 || c < code {
+			code = c
+		}
+	}
+	return code
+}
+
+// Err возвращает *FailedError, если в задании были ошибки
+func (s *Summary) Err() error {
+	if n := s.Len(); n > 0 {
+		return &FailedError{Code: s.ExitCode(), Failed: n}
+	}
+	return nil
+}
+
+// Print выводит таблицу ошибок по категориям с примерами URL
+func (s *Summary) Print(w io.Writer) error {
+	s.mu.Lock()
+	byCategory := make(map[Category][]Failure)
+	for _, failure := range s.failures {
+		byCategory[failure.Category] = append(byCategory[failure.Category], failure)
+	}
+	s.mu.Unlock()
+
+	categories := make([]Category, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if exitCodes[categories[i]] != exitCodes[categories[j]] {
+			return exitCodes[categories[i]] < exitCodes[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CATEGORY\tFAILED\tEXIT\tEXAMPLES")
+	for _, category := range categories {
+		failures := byCategory[category]
+		sort.Slice(failures, func(i, j int) bool { return failures[i].URL < failures[j].URL })
+		for i, failure := range failures[:min(len(failures), maxExamples)] {
+			if i == 0 {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%s: %v\n", category, len(failures), exitCodes[category], failure.URL, failure.Err)
+			} else {
+				fmt.Fprintf(tw, "\t\t\t%s: %v\n", failure.URL, failure.Err)
+			}
+		}
+		if len(failures) > maxExamples {
+			fmt.Fprintf(tw, "\t\t\t... and %d more\n", len(failures)-maxExamples)
+		}
+	}
+	return tw.Flush()
+}
+
+// FailedError задание завершилось, но часть ссылок обработать не удалось
+type FailedError struct {
+	Code   int // код завершения в стиле wget
+	Failed int
+}
+
+// Error реализует error
+func (e *FailedError) Error() string {
+	return fmt.Sprintf("%d URL(s) failed", e.Failed)
+}
+
+// ExitCode код завершения утилиты
+func (e *FailedError) ExitCode() int {
+	return e.Code
+}
+
+// Classify определяет категорию ошибки загрузки
+func Classify(err error) Category {
+	var loginErr *LoginError
+	if errors.As(err, &loginErr) {
+		return CategoryAuth
+	}
+
+	var statusErr *downloader.StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Code {
+		case http.StatusUnauthorized, http.StatusProxyAuthRequired:
+			return CategoryAuth
+		}
+		return CategoryServer
+	}
+
+	var (
+		certErr      *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		recordErr    tls.RecordHeaderError
+	)
+	if errors.As(err, &certErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) || errors.As(err, &recordErr) {
+		return CategoryTLS
+	}
+
+	// *url.Error сам реализует net.Error, поэтому проверяется вложенная ошибка
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return CategoryNetwork
+	}
+
+	return CategoryProtocol
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"mirror-wget/internal/downloader"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestClassify тест категорий ошибок загрузки
+func TestClassify(t *testing.T) {
+	wrap := func(err error) error {
+		return fmt.Errorf("download failed: https://example.com/ - %w", &url.Error{Op: "Get", URL: "https://example.com/", Err: err})
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		category Category
+	}{
+		{name: "not found", err: fmt.Errorf("download failed: %w", &downloader.StatusError{Code: 404}), category: CategoryServer},
+		{name: "server error", err: &downloader.StatusError{Code: 503}, category: CategoryServer},
+		{name: "unauthorized", err: &downloader.StatusError{Code: 401}, category: CategoryAuth},
+		{name: "proxy auth", err: &downloader.StatusError{Code: 407}, category: CategoryAuth},
+		{name: "connection refused", err: wrap(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}), category: CategoryNetwork},
+		{name: "dns", err: wrap(&net.DNSError{Err: "no such host", Name: "example.invalid"}), category: CategoryNetwork},
+		{name: "timeout", err: wrap(context.DeadlineExceeded), category: CategoryNetwork},
+		{name: "certificate", err: wrap(x509.UnknownAuthorityError{}), category: CategoryTLS},
+		{name: "malformed response", err: wrap(errors.New("malformed HTTP response")), category: CategoryProtocol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.category {
+				t.Errorf("expected %s, got %s", tt.category, got)
+			}
+		})
+	}
+}
+
+// TestSummary тест кода завершения и сводки ошибок
+func TestSummary(t *testing.T) {
+	s := NewSummary()
+	if s.ExitCode() != 0 || s.Err() != nil {
+		t.Fatalf("expected success for empty summary, got %d %v", s.ExitCode(), s.Err())
+	}
+
+	for n := 0; n < 5; n++ {
+		s.Add(fmt.Sprintf("https://example.com/%d", n), CategoryServer, &downloader.StatusError{Code: 404})
+	}
+	s.Add("https://example.com/private", CategoryAuth, &downloader.StatusError{Code: 401})
+	if s.ExitCode() != ExitAuth {
+		t.Errorf("expected lower exit code %d to win, got %d", ExitAuth, s.ExitCode())
+	}
+
+	var failed *FailedError
+	if err := s.Err(); !errors.As(err, &failed) || failed.Code != ExitAuth || failed.Failed != 6 {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected header, auth row, 3 server examples and remainder, got %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "auth") || !strings.HasPrefix(lines[2], "server") || !strings.Contains(lines[5], "... and 2 more") {
+		t.Errorf("unexpected summary %q", buf.String())
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"mirror-wget/internal/journal"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Previous документы, сохранённые прошлым обходом зеркала. По ним при -N
+// отправляются условные запросы, а неизменившиеся документы берутся с диска.
+// Методы безопасны для nil получателя - тогда документы всегда скачиваются заново
+type Previous struct {
+	byURL  map[string]journal.Entry
+	byPath map[string]string // путь файла -> URL
+}
+
+// NewPrevious собирает Previous из состояния прошлого задания
+func NewPrevious(state *journal.State) *Previous {
+	p := &Previous{
+		byURL:  make(map[string]journal.Entry, len(state.Done)),
+		byPath: make(map[string]string, len(state.Done)),
+	}
+	for u, entry := range state.Done {
+		if entry.Path == "" || (entry.ETag == "" && entry.LastModified == "") {
+			continue
+		}
+		p.byURL[u] = entry
+		p.byPath[filepath.Clean(entry.Path)] = u
+	}
+	return p
+}
+
+// Lookup возвращает запись о документе u, если он сохранён прошлым обходом и файл ещё на месте
+func (p *Previous) Lookup(u string) (journal.Entry, bool) {
+	if p == nil {
+		return journal.Entry{}, false
+	}
+	entry, ok := p.byURL[u]
+	if !ok {
+		return entry, false
+	}
+	if info, err := os.Stat(entry.Path); err != nil || !info.Mode().IsRegular() {
+		return entry, false
+	}
+	return entry, true
+}
+
+// OriginalLinks восстанавливает исходные URL ссылок документа docPath, которые -k заменил
+// на относительные пути к сохранённым файлам. Остальные ссылки возвращаются как есть
+func (p *Previous) OriginalLinks(docPath string, links []string) []string {
+	if p == nil {
+		return links
+	}
+
+	result := make([]string, 0, len(links))
+	for _, link := range links {
+		result = append(result, p.originalLink(docPath, link))
+	}
+	return result
+}
+
+// originalLink возвращает исходный URL ссылки link, если она ведёт на сохранённый файл
+func (p *Previous) originalLink(docPath, link string) string {
+	ref, err := url.Parse(link)
+	if err != nil || ref.IsAbs() || ref.Host != "" || ref.Path == "" || strings.HasPrefix(ref.Path, "/") {
+		return link
+	}
+
+	target := filepath.Join(filepath.Dir(docPath), filepath.FromSlash(ref.Path))
+	if original, ok := p.byPath[target]; ok {
+		if ref.Fragment != "" {
+			original += "#" + ref.Fragment
+		}
+		return original
+	}
+	return link
+}
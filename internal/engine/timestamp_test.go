@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"mirror-wget/internal/journal"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestPreviousOriginalLinks тест восстановления исходных URL ссылок, переписанных -k
+func TestPreviousOriginalLinks(t *testing.T) {
+	root := t.TempDir()
+	index := filepath.Join(root, "example.com", "index.html")
+	docs := filepath.Join(root, "example.com", "docs", "index.html")
+	logo := filepath.Join(root, "example.com", "img", "logo.png")
+	for _, path := range []string{index, docs, logo} {
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		os.WriteFile(path, nil, 0644)
+	}
+
+	previous := NewPrevious(&journal.State{Done: map[string]journal.Entry{
+		"https://example.com/":             {Path: index, ETag: `"a"`},
+		"https://example.com/docs/":        {Path: docs, LastModified: "Wed, 01 May 2024 12:00:00 GMT"},
+		"https://example.com/img/logo.png": {Path: logo, ETag: `"b"`},
+		"https://example.com/about/":       {Path: filepath.Join(root, "example.com", "about", "index.html"), ETag: `"c"`},
+		"https://example.com/feed/":        {Path: filepath.Join(root, "example.com", "feed", "index.html")},
+	}})
+
+	links := previous.OriginalLinks(docs, []string{
+		"../index.html",
+		"../img/logo.png#top",
+		"https://other.com/page",
+		"/absolute/path",
+		"missing.html",
+	})
+	expected := []string{
+		"https://example.com/",
+		"https://example.com/img/logo.png#top",
+		"https://other.com/page",
+		"/absolute/path",
+		"missing.html",
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("expected %v, got %v", expected, links)
+	}
+
+	if _, ok := previous.Lookup("https://example.com/docs/"); !ok {
+		t.Error("expected saved document to be found")
+	}
+	// файл удалён или у документа не было валидаторов - скачивается заново
+	for _, u := range []string{"https://example.com/about/", "https://example.com/feed/"} {
+		if _, ok := previous.Lookup(u); ok {
+			t.Errorf("expected %s not to be found", u)
+		}
+	}
+
+	var nilPrevious *Previous
+	if _, ok := nilPrevious.Lookup("https://example.com/"); ok {
+		t.Error("expected nil Previous to find nothing")
+	}
+}
@@ -1,15 +1,19 @@
 package engine
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"mirror-wget/internal/downloader"
+	"mirror-wget/internal/journal"
+	"mirror-wget/internal/logging"
 	"mirror-wget/internal/normalizer"
 	"mirror-wget/internal/parser"
+	"mirror-wget/internal/progress"
 	"mirror-wget/internal/queue"
-	"mirror-wget/internal/storage"
+	"mirror-wget/internal/scope"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -18,31 +22,76 @@ import (
 
 // Worker исполняет загрузку, парсинг и сохранение файла
 type Worker struct {
-	baseURL      *normalizer.NormalizedURL
 	URL          *normalizer.NormalizedURL
 	wg           *sync.WaitGroup
 	activeTasks  *int32
 	queue        queue.Queue
 	storageQueue queue.Queue
 	downloadMap  *sync.Map
+	fetcher      downloader.Fetcher
+	scope        *scope.Scope
+	layout       normalizer.Layout
+	journal      *journal.Journal
+	hosts        *HostLimiter
+	spider       *SpiderReport // в режиме --spider файлы не сохраняются, а результаты собираются сюда
+	progress     *progress.Tracker
+	summary      *Summary
+	retry        *RetryPolicy
+	maxFileSize  int64 // --max-filesize, 0 - без ограничения
+	resume       bool  // -c: продолжать прерванные загрузки
+	previous     *Previous
+	redirects    *RedirectPolicy
+	keepEncoded  bool // --keep-encoded: сохранять сжатые ответы как есть
+	session      *Session
+	id           int
+}
+
+// WorkerOptions зависимости и настройки Worker. Общие для всех воркеров структуры
+// создаёт Engine; nil значения отключают соответствующую возможность
+type WorkerOptions struct {
+	Queue        queue.Queue // ссылки для обхода
+	StorageQueue queue.Queue // документы для переписывания ссылок (-k)
+	DownloadMap  *sync.Map   // адрес - путь сохранённого файла
+	Fetcher      downloader.Fetcher
+	Scope        *scope.Scope
+	Layout       normalizer.Layout
+	Journal      *journal.Journal
+	Hosts        *HostLimiter
+	Spider       *SpiderReport // в режиме --spider файлы не сохраняются, а результаты собираются сюда
+	Progress     *progress.Tracker
+	Summary      *Summary
+	Retry        *RetryPolicy
+	Previous     *Previous // документы прошлого обхода для -N
+	Redirects    *RedirectPolicy
+	Session      *Session // вход через форму
+	MaxFileSize  int64    // --max-filesize, 0 - без ограничения
+	Resume       bool     // -c: продолжать прерванные загрузки
+	KeepEncoded  bool     // --keep-encoded: сохранять сжатые ответы как есть
 }
 
 // NewWorker инициализирует Worker
-func NewWorker(
-	baseURL *normalizer.NormalizedURL,
-	wg *sync.WaitGroup,
-	activeTasks *int32,
-	queue queue.Queue,
-	storageQueue queue.Queue,
-	downloadMap *sync.Map) *Worker {
+func NewWorker(wg *sync.WaitGroup, activeTasks *int32, opts WorkerOptions) *Worker {
 	return &Worker{
-		baseURL:      baseURL,
-		URL:          baseURL,
 		wg:           wg,
 		activeTasks:  activeTasks,
-		queue:        queue,
-		downloadMap:  downloadMap,
-		storageQueue: storageQueue,
+		queue:        opts.Queue,
+		downloadMap:  opts.DownloadMap,
+		storageQueue: opts.StorageQueue,
+		fetcher:      opts.Fetcher,
+		scope:        opts.Scope,
+		layout:       opts.Layout,
+		journal:      opts.Journal,
+		hosts:        opts.Hosts,
+		spider:       opts.Spider,
+		progress:     opts.Progress,
+		summary:      opts.Summary,
+		retry:        opts.Retry,
+		maxFileSize:  opts.MaxFileSize,
+		resume:       opts.Resume,
+		previous:     opts.Previous,
+		redirects:    opts.Redirects,
+		keepEncoded:  opts.KeepEncoded,
+		session:      opts.Session,
 	}
 }
 
@@ -50,7 +99,8 @@ func NewWorker(
 func (w *Worker) Worker(ctx context.Context, id int, jobs <-chan queue.Item) {
 	defer w.wg.Done()
 
-	log.Printf("worker %d starting\n", id)
+	w.id = id
+	logging.Trace("worker starting", "worker", id)
 
 	for {
 		select {
@@ -62,7 +112,10 @@ func (w *Worker) Worker(ctx context.Context, id int, jobs <-chan queue.Item) {
 				return
 			}
 
+			w.progress.Start(w.id, item.URL.String())
 			w.processItem(ctx, item)
+			w.progress.Finish(w.id)
+			w.hosts.Release(item.URL.URL.Host)
 			atomic.AddInt32(w.activeTasks, -1)
 		}
 	}
@@ -70,119 +123,263 @@ func (w *Worker) Worker(ctx context.Context, id int, jobs <-chan queue.Item) {
 
 // processItem работает над объектом, выполняет последовательность действий для задачи
 func (w *Worker) processItem(ctx context.Context, item queue.Item) {
-	log.Printf("Processing: %s (depth: %d)\n", item.URL, item.Depth)
 	w.URL = item.URL
 
-	var content []byte
-	var contentType string
-	var err error
-	var links []string
+	// отклонённый -A/-R документ и документы в режиме --spider не сохраняются, но ссылки из них обрабатываются
+	save := w.spider == nil && w.scope.Accepted(item.URL.URL)
+	if !save && w.spider == nil {
+		slog.Debug("rejected by -A/-R, not saving", logging.KeyURL, item.URL.String())
+	}
 
-	content, contentType, err = w.downloadFile(ctx, item)
+	start := time.Now()
+	result, err := w.downloadFile(ctx, item, save)
 	if err != nil {
-		log.Printf("download file error: %s\n", err)
+		w.handleDownloadError(item, err, time.Since(start))
+		return
+	}
+
+	if result.url != nil {
+		slog.Debug("redirected", logging.KeyURL, item.URL.String(), "to", result.url.String(), "via", result.redirects)
+		w.redirects.Arrived(item, result.url)
+	}
+
+	entry := journal.Entry{
+		Event:        journal.EventDone,
+		URL:          item.URL.String(),
+		Depth:        item.Depth,
+		Requisite:    item.Requisite,
+		Path:         result.path,
+		ContentType:  result.contentType,
+		Encoding:     result.encoding,
+		Size:         result.size,
+		SHA256:       result.sha256,
+		ETag:         result.validators.ETag,
+		LastModified: result.validators.LastModified,
+		NotModified:  result.notModified,
+		Redirects:    result.redirects,
+		Elapsed:      time.Since(start),
+	}
+	if result.url != nil {
+		entry.FinalURL = result.url.String()
+	}
+	if !result.notModified {
+		w.progress.AddBytes(entry.Size)
+	}
+	if result.url != nil {
+		// ссылки в документе разрешаются и переписываются относительно адреса после редиректов
+		item.URL = result.url
+		w.URL = result.url
+	}
+	if result.path != "" {
+		// ссылки на любой адрес цепочки редиректов ведут к сохранённому файлу
+		for _, u := range append([]string{entry.URL, entry.FinalURL}, result.redirects...) {
+			if u != "" {
+				w.downloadMap.Store(u, result.path)
+			}
+		}
+		// ссылки в неизменившемся документе переписаны прошлым обходом,
+		// а в сжатом файле их переписать нельзя
+		if !result.notModified && result.encoding == "" {
+			w.storageQueue.Push(item)
+		}
+	}
+	if w.spider != nil {
+		mediaType, _, _ := strings.Cut(result.contentType, ";")
+		spiderResult := SpiderResult{
+			URL:         entry.URL,
+			Depth:       item.Depth,
+			Status:      result.status,
+			ContentType: mediaType,
+			Size:        entry.Size,
+		}
+		if !w.scope.Accepted(item.URL.URL) {
+			spiderResult.Note = "rejected by -A/-R, would not be saved"
+		} else if entry.FinalURL != "" {
+			spiderResult.Note = "redirected to " + entry.FinalURL
+		}
+		w.spider.Add(spiderResult)
+	}
+
+	w.journal.Record(entry)
+	w.progress.Done()
+	if result.notModified {
+		slog.Info("not modified",
+			logging.KeyURL, entry.URL,
+			logging.KeyDepth, entry.Depth,
+			logging.KeyStatus, result.status,
+			logging.KeyDuration, entry.Elapsed,
+			"path", entry.Path)
+	} else {
+		slog.Info("downloaded",
+			logging.KeyURL, entry.URL,
+			logging.KeyDepth, entry.Depth,
+			logging.KeyStatus, result.status,
+			logging.KeyDuration, entry.Elapsed,
+			"size", entry.Size,
+			"path", entry.Path)
+	}
+
+	// ссылки извлекаются только из HTML и CSS
+	if result.prefix == nil {
 		return
 	}
+	if result.truncated {
+		slog.Debug("document is larger than parse limit, links after it are not extracted",
+			logging.KeyURL, entry.URL, "limit", MaxParseSize)
+	}
 
 	select {
 	case <-ctx.Done():
 		return
 	default:
-		err = w.saveFile(content, item)
+		links, requisites, err := w.parseFile(result.prefix, result.contentType, item)
 		if err != nil {
-			log.Printf("save file error: %s\n", err)
+			slog.Warn("parse failed", logging.KeyURL, item.URL.String(), logging.KeyStage, "parse", "error", err)
 			return
 		}
+		if result.notModified {
+			links = w.previous.OriginalLinks(result.path, links)
+			requisites = w.previous.OriginalLinks(result.path, requisites)
+		}
+		w.handleLinks(links, requisites, item.Depth)
 	}
+}
 
-	select {
-	case <-ctx.Done():
+// handleDownloadError повторяет неудачную загрузку или записывает ошибку
+func (w *Worker) handleDownloadError(item queue.Item, err error, elapsed time.Duration) {
+	var tle *tooLargeError
+	var ratioErr *downloader.RatioError
+	if errors.As(err, &tle) || errors.As(err, &ratioErr) {
+		slog.Warn("skipped", logging.KeyURL, item.URL.String(), logging.KeyDepth, item.Depth, "reason", err)
+		w.progress.Skipped()
+		w.journal.Record(journal.Entry{
+			Event: journal.EventSkipped,
+			URL:   item.URL.String(),
+			Depth: item.Depth,
+			Error: err.Error(),
+		})
+		if w.spider != nil {
+			w.spider.Add(SpiderResult{URL: item.URL.String(), Depth: item.Depth, Note: err.Error()})
+		}
 		return
-	default:
-		links, err = w.parseFile(content, contentType, item)
-		if err != nil {
-			log.Printf("parse file error: %s\n", err)
-			return
+	}
+
+	var re *downloader.RedirectError
+	if errors.As(err, &re) && re.Rejected {
+		slog.Info("skipped", logging.KeyURL, item.URL.String(), logging.KeyDepth, item.Depth, "reason", "redirect out of scope", "to", re.URL)
+		w.progress.Skipped()
+		w.journal.Record(journal.Entry{
+			Event: journal.EventSkipped,
+			URL:   item.URL.String(),
+			Depth: item.Depth,
+			Error: err.Error(),
+		})
+		if w.spider != nil {
+			w.spider.Add(SpiderResult{URL: item.URL.String(), Depth: item.Depth, Note: re.Error()})
 		}
+		return
+	}
 
+	var se *saveError
+	if errors.As(err, &se) {
+		slog.Warn("save failed", logging.KeyURL, item.URL.String(), logging.KeyStage, "save", "error", err)
+		w.recordFailure(item, CategoryIO, err)
+		return
 	}
 
-	select {
-	case <-ctx.Done():
+	if delay, ok := w.retry.Retry(item.Attempt, err); ok {
+		slog.Warn("download failed, retrying",
+			logging.KeyURL, item.URL.String(),
+			logging.KeyDepth, item.Depth,
+			logging.KeyStage, "download",
+			logging.KeyStatus, statusOf(err),
+			"attempt", item.Attempt+1,
+			"delay", delay,
+			"error", err)
+		w.requeue(item, delay)
 		return
-	default:
-		w.handleLinks(links, item.Depth)
+	}
+
+	slog.Warn("download failed",
+		logging.KeyURL, item.URL.String(),
+		logging.KeyDepth, item.Depth,
+		logging.KeyStage, "download",
+		logging.KeyStatus, statusOf(err),
+		logging.KeyDuration, elapsed,
+		"category", Classify(err),
+		"error", err)
+	w.recordFailure(item, Classify(err), err)
+	if w.spider != nil {
+		w.spider.Add(SpiderResult{URL: item.URL.String(), Depth: item.Depth, Status: statusOf(err), Note: err.Error()})
 	}
 }
 
-// handleLinks помещает ссылки в очередь
-func (w *Worker) handleLinks(links []string, depth int) {
-	fmt.Printf("\n\n!=!=!=!=!=!=!=!=!=!=!URL: %v\n\n", w.URL.String())
+// handleLinks помещает ссылки, входящие в область обхода, в очередь
+func (w *Worker) handleLinks(links, requisites []string, depth int) {
+	isRequisite := make(map[string]bool, len(requisites))
+	for _, link := range requisites {
+		isRequisite[link] = true
+	}
+
 	for _, link := range links {
 		newNorm, err := w.URL.Normalize(link)
 		if err != nil {
-			log.Printf("Normalize failed: %s - %v\n", link, err)
+			slog.Debug("normalize failed", logging.KeyURL, w.URL.String(), "link", link, "error", err)
 			continue
 		}
 
-		if newNorm.GetHost() == w.baseURL.GetHost() {
-			fmt.Printf("Link: %s -> %s\n", link, newNorm.String())
+		if w.scope.Allowed(newNorm.URL) {
 			queueItem := queue.Item{
-				URL:   newNorm,
-				Depth: depth + 1,
+				URL:       newNorm,
+				Depth:     depth + 1,
+				Requisite: isRequisite[link],
+				Referer:   w.URL.String(),
 			}
 			ok := w.queue.Push(queueItem)
 			if ok {
 				atomic.AddInt32(w.activeTasks, 1)
+				w.journal.Queued(newNorm.String(), queueItem.Depth, queueItem.Requisite)
+				logging.Trace("queued", logging.KeyURL, newNorm.String(), logging.KeyDepth, queueItem.Depth, "from", w.URL.String())
 			}
 		}
 	}
-	fmt.Printf("\n!=!=!=!=!=!=!=!=!=!=!\n\n")
 }
 
-// downloadFile скачивает файл
-func (w *Worker) downloadFile(ctx context.Context, item queue.Item) ([]byte, string, error) {
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	// Скачиваем контент
-	log.Printf("Downloading %s (depth: %d)\n", item.URL, item.Depth)
-	content, contentType, err := downloader.Get(ctxWithTimeout, item.URL.String())
-	if err != nil {
-		return nil, contentType, fmt.Errorf("download failed: %s - %v", item.URL.String(), err)
-	}
-	log.Printf("Downloaded %s (contentType: %s)\n", item.URL.String(), contentType)
-
-	contentBytes, err := io.ReadAll(content)
-	if err != nil {
-		return nil, contentType, fmt.Errorf("download failed: %s - %v", item.URL.String(), err)
+// requeue ставит неудачную загрузку в очередь на повтор не раньше, чем через delay
+func (w *Worker) requeue(item queue.Item, delay time.Duration) {
+	item.Attempt++
+	item.NotBefore = time.Now().Add(delay)
+	if w.queue.Push(item) {
+		atomic.AddInt32(w.activeTasks, 1)
 	}
+}
 
-	return contentBytes, contentType, nil
+// recordFailure записывает ошибку обработки задачи в журнал и итоговую сводку
+func (w *Worker) recordFailure(item queue.Item, category Category, err error) {
+	w.summary.Add(item.URL.String(), category, err)
+	w.progress.Failed()
+	w.journal.Record(journal.Entry{
+		Event:     journal.EventFailed,
+		URL:       item.URL.String(),
+		Depth:     item.Depth,
+		Requisite: item.Requisite,
+		Error:     err.Error(),
+		Status:    statusOf(err),
+		Category:  string(category),
+	})
 }
 
-// saveFile сохраняет файл
-func (w *Worker) saveFile(content []byte, item queue.Item) error {
-	filePath, err := item.URL.SavePath()
-	if err != nil {
-		return fmt.Errorf("save path failed: %s - %v", item.URL.String(), err)
+// statusOf возвращает код ответа сервера из ошибки загрузки, 0 - если ответа не было
+func statusOf(err error) int {
+	var statusErr *downloader.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
 	}
-
-	log.Printf("Saving %s (filepath: %s, len: %d)\n", item.URL, filePath, len(content))
-	n, err := storage.Save(filePath, content)
-	if err != nil {
-		return fmt.Errorf("save failed: %s - %v", filePath, err)
-	}
-	log.Printf("Saved %s (%d bytes)\n", item.URL, n)
-
-	w.downloadMap.Store(item.URL.String(), filePath)
-	w.storageQueue.Push(item)
-
-	return nil
+	return 0
 }
 
 // parseFile парсит файл, извлекает ссылки из файла
-func (w *Worker) parseFile(content []byte, contentType string, item queue.Item) ([]string, error) {
+func (w *Worker) parseFile(content []byte, contentType string, item queue.Item) ([]string, []string, error) {
 	var p parser.LinkParser
 	if downloader.IsHTML(contentType) {
 		p = parser.NewHTMLParser()
@@ -192,14 +389,13 @@ func (w *Worker) parseFile(content []byte, contentType string, item queue.Item)
 		p = parser.NewDefaultParser()
 	}
 
-	log.Printf("Parsing %s\n", item.URL)
-	err := p.Parse(strings.NewReader(string(content)))
+	err := p.Parse(bytes.NewReader(content))
 	if err != nil {
-		return nil, fmt.Errorf("parse failed: %s - %v", item.URL.String(), err)
+		return nil, nil, fmt.Errorf("parse failed: %s - %v", item.URL.String(), err)
 	}
 
 	links := p.GetLinks()
-	log.Printf("Parsed %s, got %d links\n", item.URL.String(), len(links))
+	slog.Debug("parsed", logging.KeyURL, item.URL.String(), logging.KeyStage, "parse", "links", len(links))
 
-	return links, nil
+	return links, p.GetRequisites(), nil
 }
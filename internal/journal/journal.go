@@ -0,0 +1,238 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DirName каталог внутри зеркала, в котором хранится состояние задания
+const DirName = ".mirror-wget"
+
+const (
+	journalFile = "journal.jsonl"
+	configFile  = "config.json"
+)
+
+// Event тип записи журнала
+type Event string
+
+const (
+	EventQueued    Event = "queued"    // ссылка поставлена в очередь
+	EventDone      Event = "done"      // документ скачан (и сохранён, если Path не пуст)
+	EventFailed    Event = "failed"    // документ скачать или сохранить не удалось
+	EventSkipped   Event = "skipped"   // ссылка отброшена: глубина, robots.txt, -A/-R; причина в Error
+	EventRewritten Event = "rewritten" // ссылки в сохранённом документе переписаны (-k)
+	EventFinished  Event = "finished"  // задание завершено
+)
+
+// Entry запись журнала задания
+type Entry struct {
+	Event        Event         `json:"event"`
+	URL          string        `json:"url,omitempty"`
+	Depth        int           `json:"depth,omitempty"`
+	Requisite    bool          `json:"requisite,omitempty"`
+	Path         string        `json:"path,omitempty"` // путь относительно корня зеркала
+	ContentType  string        `json:"content_type,omitempty"`
+	Encoding     string        `json:"content_encoding,omitempty"` // файл сохранён сжатым (--keep-encoded)
+	Size         int64         `json:"size,omitempty"`
+	SHA256       string        `json:"sha256,omitempty"`
+	ETag         string        `json:"etag,omitempty"`
+	LastModified string        `json:"last_modified,omitempty"`
+	NotModified  bool          `json:"not_modified,omitempty"` // документ не изменился с прошлого обхода (-N)
+	FinalURL     string        `json:"final_url,omitempty"`    // адрес, на который привели редиректы
+	Redirects    []string      `json:"redirects,omitempty"`    // промежуточные адреса цепочки редиректов
+	Elapsed      time.Duration `json:"elapsed,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	Status       int           `json:"status,omitempty"`   // код ответа сервера неудачной загрузки
+	Category     string        `json:"category,omitempty"` // категория ошибки неудачной загрузки
+	Time         time.Time     `json:"time"`
+}
+
+// Journal журнал задания: конфигурация и последовательность событий обхода.
+// По нему задание продолжается командой resume и проверяется командами verify и report.
+// Методы безопасны для nil получателя - журнал тогда не ведётся
+type Journal struct {
+	root   string
+	mu     sync.Mutex
+	file   *os.File
+	queued map[string]bool
+}
+
+// Create начинает новый журнал в корне зеркала root и сохраняет конфигурацию задания
+func Create(root string, config any) (*Journal, error) {
+	if err := os.MkdirAll(stateDir(root), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(stateDir(root), configFile), data, 0644); err != nil {
+		return nil, err
+	}
+
+	return open(root, os.O_CREATE|os.O_TRUNC|os.O_WRONLY)
+}
+
+// Open открывает существующий журнал для дописывания
+func Open(root string) (*Journal, error) {
+	return open(root, os.O_CREATE|os.O_APPEND|os.O_WRONLY)
+}
+
+// open открывает файл журнала с флагами flag
+func open(root string, flag int) (*Journal, error) {
+	f, err := os.OpenFile(filepath.Join(stateDir(root), journalFile), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{
+		root:   root,
+		file:   f,
+		queued: make(map[string]bool),
+	}, nil
+}
+
+// Root возвращает корень зеркала
+func (j *Journal) Root() string {
+	if j == nil {
+		return ""
+	}
+	return j.root
+}
+
+// Record добавляет запись в журнал. Путь приводится к пути относительно корня зеркала
+func (j *Journal) Record(entry Entry) error {
+	if j == nil {
+		return nil
+	}
+
+	if entry.Path != "" {
+		if rel, err := filepath.Rel(rootDir(j.root), entry.Path); err == nil {
+			entry.Path = rel
+		}
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.file.Write(append(data, '\n'))
+	return err
+}
+
+// Queued записывает постановку ссылки в очередь; повторные ссылки не записываются
+func (j *Journal) Queued(url string, depth int, requisite bool) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	seen := j.queued[url]
+	j.queued[url] = true
+	j.mu.Unlock()
+	if seen {
+		return nil
+	}
+
+	return j.Record(Entry{Event: EventQueued, URL: url, Depth: depth, Requisite: requisite})
+}
+
+// Finish отмечает задание завершённым
+func (j *Journal) Finish() error {
+	return j.Record(Entry{Event: EventFinished})
+}
+
+// Close закрывает журнал
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// ReadConfig читает сохранённую конфигурацию задания в v
+func ReadConfig(root string, v any) error {
+	data, err := os.ReadFile(filepath.Join(stateDir(root), configFile))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ReadEntries читает все записи журнала задания
+func ReadEntries(root string) ([]Entry, error) {
+	f, err := os.Open(filepath.Join(stateDir(root), journalFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var entry Entry
+			// последняя строка может быть оборвана, если процесс был убит во время записи
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr == nil {
+				entries = append(entries, entry)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// HashFile возвращает размер и sha256 файла
+func HashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashBytes возвращает sha256 данных
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// stateDir каталог состояния задания в корне зеркала
+func stateDir(root string) string {
+	return filepath.Join(rootDir(root), DirName)
+}
+
+// rootDir корень зеркала; пустой корень - текущий каталог
+func rootDir(root string) string {
+	if root == "" {
+		return "."
+	}
+	return root
+}
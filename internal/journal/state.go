@@ -0,0 +1,178 @@
+package journal
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// State состояние задания, восстановленное из журнала
+type State struct {
+	Root     string
+	Entries  []Entry
+	Done     map[string]Entry // последняя запись done или rewritten по URL; Path - путь от текущего каталога
+	Failed   map[string]Entry // URL, которые не удалось скачать и которые так и не скачались позже
+	Skipped  map[string]Entry // URL, отброшенные при обходе
+	Pending  []Entry          // ссылки, поставленные в очередь, но ещё не обработанные; неудачные - в Failed
+	Finished bool
+}
+
+// Load восстанавливает состояние задания из журнала в корне зеркала root
+func Load(root string) (*State, error) {
+	entries, err := ReadEntries(root)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{
+		Root:    root,
+		Entries: entries,
+		Done:    make(map[string]Entry),
+		Failed:  make(map[string]Entry),
+		Skipped: make(map[string]Entry),
+	}
+
+	queued := make(map[string]Entry)
+	var order []string
+	for _, entry := range entries {
+		if entry.Path != "" {
+			entry.Path = filepath.Join(rootDir(root), entry.Path)
+		}
+
+		switch entry.Event {
+		case EventQueued:
+			prev, ok := queued[entry.URL]
+			if !ok {
+				order = append(order, entry.URL)
+			}
+			if !ok || entry.Depth < prev.Depth {
+				queued[entry.URL] = entry
+			}
+		case EventDone, EventRewritten:
+			if prev, ok := state.Done[entry.URL]; ok && entry.Event == EventRewritten {
+				// после переписывания меняются только размер и хеш файла
+				prev.Size, prev.SHA256 = entry.Size, entry.SHA256
+				entry = prev
+			}
+			state.Done[entry.URL] = entry
+			delete(state.Failed, entry.URL)
+		case EventFailed:
+			if _, ok := state.Done[entry.URL]; !ok {
+				state.Failed[entry.URL] = entry
+			}
+		case EventSkipped:
+			state.Skipped[entry.URL] = entry
+		case EventFinished:
+			state.Finished = true
+		}
+	}
+
+	for _, url := range order {
+		_, done := state.Done[url]
+		_, skipped := state.Skipped[url]
+		_, failed := state.Failed[url]
+		if !done && !skipped && !failed {
+			state.Pending = append(state.Pending, queued[url])
+		}
+	}
+
+	return state, nil
+}
+
+// Stats сводная статистика задания для команды report
+type Stats struct {
+	Queued        int
+	Done          int
+	Saved         int
+	Failed        int
+	Skipped       int
+	Bytes         int64
+	Elapsed       time.Duration // от первой до последней записи журнала
+	ByContentType []Bucket
+	ByDepth       []Bucket
+	Failures      []Entry
+}
+
+// Bucket количество документов и байт в группе
+type Bucket struct {
+	Key   string
+	Count int
+	Bytes int64
+}
+
+// Stats считает статистику по состоянию задания
+func (s *State) Stats() Stats {
+	stats := Stats{
+		Done:    len(s.Done),
+		Failed:  len(s.Failed),
+		Skipped: len(s.Skipped),
+	}
+
+	byType := make(map[string]*Bucket)
+	byDepth := make(map[int]*Bucket)
+	for _, entry := range s.Done {
+		if entry.Path != "" {
+			stats.Saved++
+		}
+		stats.Bytes += entry.Size
+
+		contentType, _, _ := strings.Cut(entry.ContentType, ";")
+		if contentType == "" {
+			contentType = "unknown"
+		}
+		addToBucket(byType, contentType, contentType, entry.Size)
+		addToBucket(byDepth, entry.Depth, strconv.Itoa(entry.Depth), entry.Size)
+	}
+
+	var first, last time.Time
+	for _, entry := range s.Entries {
+		if entry.Event == EventQueued {
+			stats.Queued++
+		}
+		if first.IsZero() || entry.Time.Before(first) {
+			first = entry.Time
+		}
+		if entry.Time.After(last) {
+			last = entry.Time
+		}
+	}
+	stats.Elapsed = last.Sub(first)
+
+	for _, entry := range s.Failed {
+		stats.Failures = append(stats.Failures, entry)
+	}
+	sort.Slice(stats.Failures, func(i, j int) bool { return stats.Failures[i].URL < stats.Failures[j].URL })
+
+	stats.ByContentType = sortedBuckets(byType, func(a, b string) bool { return byType[a].Bytes > byType[b].Bytes })
+	stats.ByDepth = sortedBuckets(byDepth, func(a, b int) bool { return a < b })
+
+	return stats
+}
+
+// addToBucket добавляет документ в группу
+func addToBucket[K comparable](buckets map[K]*Bucket, key K, name string, size int64) {
+	b, ok := buckets[key]
+	if !ok {
+		b = &Bucket{Key: name}
+		buckets[key] = b
+	}
+	b.Count++
+	b.Bytes += size
+}
+
+// sortedBuckets возвращает группы, упорядоченные по ключам функцией less
+func sortedBuckets[K comparable](buckets map[K]*Bucket, less func(a, b K) bool) []Bucket {
+	keys := make([]K, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+
+	result := make([]Bucket, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *buckets[key])
+	}
+	return result
+}
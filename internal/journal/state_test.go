@@ -0,0 +1,51 @@
+package journal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestLoad тест восстановления состояния задания из журнала
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	jr, err := Create(root, map[string]string{"urls": "https://example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jr.Queued("https://example.com/", 0, false)
+	jr.Queued("https://example.com/a", 1, false)
+	jr.Queued("https://example.com/a", 1, false)
+	jr.Queued("https://example.com/b", 1, false)
+	jr.Queued("https://example.com/c", 1, false)
+	jr.Record(Entry{Event: EventDone, URL: "https://example.com/", Path: filepath.Join(root, "index.html"), Size: 10, SHA256: "old"})
+	jr.Record(Entry{Event: EventRewritten, URL: "https://example.com/", Size: 12, SHA256: "new"})
+	jr.Record(Entry{Event: EventFailed, URL: "https://example.com/a", Error: "status code 500"})
+	jr.Record(Entry{Event: EventSkipped, URL: "https://example.com/c", Error: "disallowed by robots.txt"})
+	jr.Close()
+
+	state, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := state.Done["https://example.com/"]
+	if done.Path != filepath.Join(root, "index.html") || done.Size != 12 || done.SHA256 != "new" {
+		t.Errorf("unexpected done entry %+v", done)
+	}
+	if _, ok := state.Failed["https://example.com/a"]; !ok || len(state.Failed) != 1 {
+		t.Errorf("unexpected failed entries %v", state.Failed)
+	}
+	// неудачная загрузка не считается ожидающей: повторять ли её, решает resume
+	if len(state.Pending) != 1 || state.Pending[0].URL != "https://example.com/b" {
+		t.Errorf("unexpected pending entries %v", state.Pending)
+	}
+	if state.Finished {
+		t.Error("expected unfinished job")
+	}
+
+	stats := state.Stats()
+	if stats.Queued != 4 || stats.Done != 1 || stats.Saved != 1 || stats.Failed != 1 || stats.Skipped != 1 || stats.Bytes != 12 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
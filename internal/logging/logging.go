@@ -0,0 +1,166 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// LevelTrace уровень самых подробных сообщений (-vv): простой очереди, запуск воркеров
+const LevelTrace = slog.LevelDebug - 4
+
+// Форматы журнала
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Имена полей сообщений о документах
+const (
+	KeyURL      = "url"
+	KeyDepth    = "depth"
+	KeyStage    = "stage"
+	KeyStatus   = "status"
+	KeyDuration = "duration"
+)
+
+// Options настройки журнала
+type Options struct {
+	Quiet     bool   `json:"quiet"`         // -q: только ошибки
+	Verbose   int    `json:"verbose"`       // -v: подробный журнал, -vv: трассировка
+	Format    string `json:"log_format"`    // --log-format: text или json
+	File      string `json:"output_file"`   // -o: писать журнал в файл, перезаписывая его
+	AppendLog string `json:"append_output"` // -a: дописывать журнал в конец файла
+}
+
+// RegisterFlags описывает флаги журнала в наборе fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Quiet, "q", o.Quiet, "quiet (no output except errors)")
+	fs.BoolVar(&o.Quiet, "quiet", o.Quiet, "quiet (no output except errors)")
+	fs.Var(&verboseValue{level: &o.Verbose, step: 1}, "v", "be verbose (-vv for trace output)")
+	fs.Var(&verboseValue{level: &o.Verbose, step: 1}, "verbose", "be verbose (-vv for trace output)")
+	fs.Var(&verboseValue{level: &o.Verbose, step: 2}, "vv", "trace output")
+	fs.StringVar(&o.Format, "log-format", o.Format, "log `FORMAT`: text or json")
+	fs.StringVar(&o.File, "o", o.File, "log messages to `FILE`")
+	fs.StringVar(&o.File, "output-file", o.File, "log messages to `FILE`")
+	fs.StringVar(&o.AppendLog, "a", o.AppendLog, "append messages to `FILE`")
+	fs.StringVar(&o.AppendLog, "append-output", o.AppendLog, "append messages to `FILE`")
+}
+
+// Validate проверяет настройки журнала
+func (o Options) Validate() error {
+	switch o.Format {
+	case "", FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q: expected text or json", o.Format)
+	}
+	if o.File != "" && o.AppendLog != "" {
+		return errors.New("-o and -a are mutually exclusive")
+	}
+	return nil
+}
+
+// Level возвращает минимальный уровень сообщений
+func (o Options) Level() slog.Level {
+	switch {
+	case o.Quiet:
+		return slog.LevelError
+	case o.Verbose >= 2:
+		return LevelTrace
+	case o.Verbose == 1:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Setup настраивает журнал по умолчанию (slog.Default) и возвращает функцию,
+// закрывающую файл журнала. Без -o и -a журнал пишется в stderr
+func Setup(o Options, stderr io.Writer) (func() error, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	w := stderr
+	closeFn := func() error { return nil }
+	if path, flag := o.file(); path != "" {
+		f, err := os.OpenFile(path, flag, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w, closeFn = f, f.Close
+	}
+
+	slog.SetDefault(New(w, o.Level(), o.Format))
+	return closeFn, nil
+}
+
+// New создаёт журнал с уровнем level в формате format
+func New(w io.Writer, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevel}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Trace пишет сообщение уровня LevelTrace
+func Trace(msg string, args ...any) {
+	slog.Log(context.Background(), LevelTrace, msg, args...)
+}
+
+// file файл журнала и флаги его открытия
+func (o Options) file() (string, int) {
+	if o.AppendLog != "" {
+		return o.AppendLog, os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	}
+	return o.File, os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+}
+
+// replaceLevel называет уровень LevelTrace "TRACE" вместо "DEBUG-4"
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
+
+// verboseValue flag.Value для -v и -vv: каждое появление флага повышает подробность на step
+type verboseValue struct {
+	level *int
+	step  int
+}
+
+// Set реализует flag.Value
+func (v *verboseValue) Set(s string) error {
+	enabled, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		*v.level += v.step
+	} else {
+		*v.level = 0
+	}
+	return nil
+}
+
+// String реализует flag.Value
+func (v *verboseValue) String() string {
+	if v == nil || v.level == nil {
+		return "0"
+	}
+	return strconv.Itoa(*v.level)
+}
+
+// IsBoolFlag позволяет писать -v без значения
+func (v *verboseValue) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestOptionsLevel тест уровней журнала для -q, -v и -vv
+func TestOptionsLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		level slog.Level
+	}{
+		{name: "default", args: nil, level: slog.LevelInfo},
+		{name: "quiet", args: []string{"-q"}, level: slog.LevelError},
+		{name: "verbose", args: []string{"-v"}, level: slog.LevelDebug},
+		{name: "trace", args: []string{"-vv"}, level: LevelTrace},
+		{name: "repeated verbose", args: []string{"-v", "--verbose"}, level: LevelTrace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o Options
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			o.RegisterFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if o.Level() != tt.level {
+				t.Errorf("expected level %v, got %v", tt.level, o.Level())
+			}
+		})
+	}
+}
+
+// TestOptionsValidate тест проверки настроек журнала
+func TestOptionsValidate(t *testing.T) {
+	if err := (Options{Format: "xml"}).Validate(); err == nil {
+		t.Error("expected error for unknown format, got nil")
+	}
+	if err := (Options{File: "a.log", AppendLog: "b.log"}).Validate(); err == nil {
+		t.Error("expected error for -o with -a, got nil")
+	}
+	if err := (Options{Format: FormatJSON, AppendLog: "b.log"}).Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// TestNewJSON тест журнала в формате JSON с полями документа
+func TestNewJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelTrace, FormatJSON)
+	logger.Log(context.Background(), LevelTrace, "queued", KeyURL, "https://example.com/", KeyDepth, 1)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if record["level"] != "TRACE" || record["msg"] != "queued" || record[KeyURL] != "https://example.com/" || record[KeyDepth] != 1.0 {
+		t.Errorf("unexpected record %v", record)
+	}
+
+	buf.Reset()
+	New(&buf, slog.LevelInfo, FormatText).Debug("hidden")
+	if strings.TrimSpace(buf.String()) != "" {
+		t.Errorf("expected debug message to be filtered, got %q", buf.String())
+	}
+}
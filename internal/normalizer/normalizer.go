@@ -39,8 +39,10 @@ func (n *NormalizedURL) Normalize(ref string) (*NormalizedURL, error) {
 	u.Scheme = strings.ToLower(u.Scheme)
 	u.Host = strings.ToLower(u.Host)
 
-	if u.Host != n.URL.Host {
-		return nil, errors.New("host does not match")
+	// ссылки вида mailto:, javascript:, data: и т.п. не скачиваются;
+	// принадлежность хоста к области обхода решает вызывающая сторона
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("unsupported scheme")
 	}
 
 	if u.Path != "" {
@@ -64,9 +66,21 @@ func (n *NormalizedURL) String() string {
 	return str
 }
 
+// Layout описывает раскладку сохраняемых файлов на диске
+type Layout struct {
+	Prefix     string // каталог, в который сохраняется зеркало (-P)
+	NoHostDirs bool   // не создавать каталог с именем хоста (-nH)
+	CutDirs    int    // сколько первых каталогов пути отбросить (--cut-dirs)
+}
+
 // SavePath возвращает путь по которому нужно сохранить документ
 func (n *NormalizedURL) SavePath() (string, error) {
-	return buildSavePath(n.URL)
+	return buildSavePath(n.URL, Layout{})
+}
+
+// SavePathIn возвращает путь сохранения документа с учётом раскладки layout
+func (n *NormalizedURL) SavePathIn(layout Layout) (string, error) {
+	return buildSavePath(n.URL, layout)
 }
 
 // GetHost возвращает хост адреса
@@ -75,21 +89,30 @@ func (n *NormalizedURL) GetHost() string {
 }
 
 // buildSavePath делает путь для сохранения
-func buildSavePath(u *url.URL) (string, error) {
-	host := u.Host
+func buildSavePath(u *url.URL, layout Layout) (string, error) {
 	p := u.Path
+	dir, file := p, "index.html"
+
+	// Если путь не пустой, не заканчивается на / и есть расширение - это файл,
+	// иначе считаем директорией
+	if p != "" && !strings.HasSuffix(p, "/") && filepath.Ext(p) != "" {
+		dir, file = path.Split(p)
+	}
 
-	// Если путь пустой или заканчивается на / - это директория
-	if p == "" || strings.HasSuffix(p, "/") {
-		return filepath.Join(host, p, "index.html"), nil
+	dirs := strings.FieldsFunc(dir, func(r rune) bool { return r == '/' })
+	if layout.CutDirs >= len(dirs) {
+		dirs = nil
+	} else if layout.CutDirs > 0 {
+		dirs = dirs[layout.CutDirs:]
 	}
 
-	// Если есть расширение - это файл
-	ext := filepath.Ext(p)
-	if ext != "" {
-		return filepath.Join(host, p), nil
+	elems := make([]string, 0, len(dirs)+3)
+	elems = append(elems, layout.Prefix)
+	if !layout.NoHostDirs {
+		elems = append(elems, u.Host)
 	}
+	elems = append(elems, dirs...)
+	elems = append(elems, file)
 
-	// Нет расширения - считаем директорией
-	return filepath.Join(host, p, "index.html"), nil
+	return filepath.Join(elems...), nil
 }
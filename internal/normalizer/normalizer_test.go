@@ -81,7 +81,7 @@ func TestNormalize(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			n, err := NewNormalizedUrl(tt.base)
+			n, err := NewNormalizedURL(tt.base)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -107,7 +107,7 @@ func TestNormalize(t *testing.T) {
 
 // TestNormalizeInvalidBase тест ошибка невалидного url
 func TestNormalizeInvalidBase(t *testing.T) {
-	_, err := NewNormalizedUrl(":://bad_url")
+	_, err := NewNormalizedURL(":://bad_url")
 	if err == nil {
 		t.Error("expected error for invalid base url, got nil")
 	}
@@ -115,7 +115,7 @@ func TestNormalizeInvalidBase(t *testing.T) {
 
 // TestNormalizeInvalidRef тест невалидного относительного пути
 func TestNormalizeInvalidRef(t *testing.T) {
-	n, err := NewNormalizedUrl("https://example.com")
+	n, err := NewNormalizedURL("https://example.com")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -125,3 +125,75 @@ func TestNormalizeInvalidRef(t *testing.T) {
 		t.Error("expected error for invalid ref url, got nil")
 	}
 }
+
+// TestNormalizeUnsupportedScheme тест ссылок, которые не скачиваются
+func TestNormalizeUnsupportedScheme(t *testing.T) {
+	n, err := NewNormalizedURL("https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"mailto:test@example.com", "javascript:void(0)", "ftp://example.com/file.txt"} {
+		if _, err := n.Normalize(ref); err == nil {
+			t.Errorf("expected error for %q, got nil", ref)
+		}
+	}
+}
+
+// TestSavePathIn тест пути сохранения с учётом раскладки
+func TestSavePathIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		layout   Layout
+		expected string
+	}{
+		{
+			name:     "default layout",
+			url:      "https://example.com/a/b/c.html",
+			layout:   Layout{},
+			expected: "example.com/a/b/c.html",
+		},
+		{
+			name:     "prefix",
+			url:      "https://example.com/a/",
+			layout:   Layout{Prefix: "out"},
+			expected: "out/example.com/a/index.html",
+		},
+		{
+			name:     "no host directories",
+			url:      "https://example.com/a/b/c.html",
+			layout:   Layout{NoHostDirs: true},
+			expected: "a/b/c.html",
+		},
+		{
+			name:     "cut dirs",
+			url:      "https://example.com/a/b/c.html",
+			layout:   Layout{CutDirs: 1},
+			expected: "example.com/b/c.html",
+		},
+		{
+			name:     "cut more dirs than path has",
+			url:      "https://example.com/a/b/",
+			layout:   Layout{Prefix: "out", NoHostDirs: true, CutDirs: 5},
+			expected: "out/index.html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNormalizedURL(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := n.SavePathIn(tt.layout)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -54,6 +54,11 @@ func (p *CSSParser) GetLinks() []string {
 	return links
 }
 
+// GetRequisites возвращает ссылки на ресурсы; в CSS все ссылки - ресурсы страницы
+func (p *CSSParser) GetRequisites() []string {
+	return p.GetLinks()
+}
+
 // postProcessAndAddLink обрабатывает ссылку и добавляет ее к множеству ссылок
 func (p *CSSParser) postProcessAndAddLink(link string) {
 	link = strings.TrimRight(link, "/")
@@ -20,3 +20,8 @@ func (d DefaultParser) Parse(_ io.Reader) error {
 func (d DefaultParser) GetLinks() []string {
 	return []string{}
 }
+
+// GetRequisites ничего не делает
+func (d DefaultParser) GetRequisites() []string {
+	return []string{}
+}
@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"strings"
+)
+
+// Form HTML форма: куда и каким методом она отправляется и поля со значениями по умолчанию
+type Form struct {
+	ID       string
+	Name     string
+	Action   string // как в атрибуте; пустой - адрес страницы
+	Method   string // в нижнем регистре, по умолчанию get
+	Fields   []FormField
+	Password string // имя первого поля пароля; пустое, если его нет
+}
+
+// FormField поле формы
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// skippedInputs типы <input>, которые не отправляются без нажатия или выбора пользователя
+var skippedInputs = map[string]bool{
+	"submit": true,
+	"button": true,
+	"image":  true,
+	"reset":  true,
+	"file":   true,
+}
+
+// ParseForms извлекает формы HTML документа со значениями полей, которые отправил бы браузер:
+// скрытые поля (CSRF токены), заполненные поля, отмеченные флажки и выбранные пункты списков
+func ParseForms(r io.Reader) ([]Form, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var forms []Form
+	// current - индекс формы, внутри которой находится элемент, -1 вне форм
+	var walk func(n *html.Node, current int)
+	walk = func(n *html.Node, current int) {
+		if n.Type == html.ElementNode {
+			var form *Form
+			if current >= 0 {
+				form = &forms[current]
+			}
+			switch n.DataAtom {
+			case atom.Form:
+				method := strings.ToLower(strings.TrimSpace(attr(n, "method")))
+				if method == "" {
+					method = "get"
+				}
+				forms = append(forms, Form{
+					ID:     attr(n, "id"),
+					Name:   attr(n, "name"),
+					Action: strings.TrimSpace(attr(n, "action")),
+					Method: method,
+				})
+				current = len(forms) - 1
+			case atom.Input:
+				addInput(n, form)
+			case atom.Textarea:
+				if name := attr(n, "name"); form != nil && name != "" {
+					form.Fields = append(form.Fields, FormField{Name: name, Value: text(n)})
+				}
+			case atom.Select:
+				if name := attr(n, "name"); form != nil && name != "" {
+					form.Fields = append(form.Fields, FormField{Name: name, Value: selected(n)})
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, current)
+		}
+	}
+	walk(doc, -1)
+	return forms, nil
+}
+
+// addInput добавляет в форму значение поля <input>
+func addInput(n *html.Node, form *Form) {
+	name := attr(n, "name")
+	if form == nil || name == "" {
+		return
+	}
+	typ := strings.ToLower(attr(n, "type"))
+	switch {
+	case skippedInputs[typ]:
+		return
+	case typ == "checkbox" || typ == "radio":
+		if !hasAttr(n, "checked") {
+			return
+		}
+		value, ok := attrOk(n, "value")
+		if !ok {
+			value = "on"
+		}
+		form.Fields = append(form.Fields, FormField{Name: name, Value: value})
+		return
+	case typ == "password" && form.Password == "":
+		form.Password = name
+	}
+	form.Fields = append(form.Fields, FormField{Name: name, Value: attr(n, "value")})
+}
+
+// selected значение выбранного пункта списка, по умолчанию первого
+func selected(n *html.Node) string {
+	var first, chosen *html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Option {
+			if first == nil {
+				first = n
+			}
+			if chosen == nil && hasAttr(n, "selected") {
+				chosen = n
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	if chosen == nil {
+		chosen = first
+	}
+	if chosen == nil {
+		return ""
+	}
+	if value, ok := attrOk(chosen, "value"); ok {
+		return value
+	}
+	return strings.TrimSpace(text(chosen))
+}
+
+// text текстовое содержимое элемента
+func text(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+// attr значение атрибута key; пустое, если его нет
+func attr(n *html.Node, key string) string {
+	value, _ := attrOk(n, key)
+	return value
+}
+
+// attrOk значение атрибута key и есть ли он
+func attrOk(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// hasAttr есть ли у элемента атрибут key
+func hasAttr(n *html.Node, key string) bool {
+	_, ok := attrOk(n, key)
+	return ok
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseForms тест извлечения форм и значений их полей
+func TestParseForms(t *testing.T) {
+	tests := []struct {
+		name   string
+		html   string
+		expect []Form
+	}{
+		{
+			name:   "no forms",
+			html:   `<p>nothing</p><input name="orphan" value="x">`,
+			expect: nil,
+		},
+		{
+			name: "login form with csrf token",
+			html: `<form id="login" action="/session" method="POST">
+				<input type="hidden" name="csrf" value="t0k3n">
+				<input name="user"><input type="password" name="pass">
+				<input type="submit" name="go" value="Sign in"></form>`,
+			expect: []Form{{
+				ID: "login", Action: "/session", Method: "post", Password: "pass",
+				Fields: []FormField{{"csrf", "t0k3n"}, {"user", ""}, {"pass", ""}},
+			}},
+		},
+		{
+			name: "checkboxes, selects and textareas",
+			html: `<form name="prefs"><input type="checkbox" name="remember" checked>
+				<input type="checkbox" name="spam" value="yes">
+				<select name="lang"><option value="en">English</option><option value="ru" selected>Русский</option></select>
+				<select name="tz"><option>UTC</option></select>
+				<textarea name="note">hi</textarea></form>
+				<form action="/search"><input name="q" value="go"></form>`,
+			expect: []Form{
+				{Name: "prefs", Method: "get", Fields: []FormField{{"remember", "on"}, {"lang", "ru"}, {"tz", "UTC"}, {"note", "hi"}}},
+				{Action: "/search", Method: "get", Fields: []FormField{{"q", "go"}}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forms, err := ParseForms(strings.NewReader(test.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(forms, test.expect) {
+				t.Errorf("expected %+v, got %+v", test.expect, forms)
+			}
+		})
+	}
+}
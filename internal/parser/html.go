@@ -27,15 +27,24 @@ var SrcAtoms = map[atom.Atom]bool{
 	atom.Video:  true, // <video>
 }
 
+// RequisiteRels множество значений rel тега <link>, указывающих на ресурс, нужный для отображения страницы
+var RequisiteRels = map[string]bool{
+	"stylesheet":    true,
+	"icon":          true,
+	"shortcut icon": true,
+}
+
 // HTMLParser представляет структуру, которая хранит ссылки, извлеченные из HTML
 type HTMLParser struct {
-	Links map[string]bool
+	Links      map[string]bool
+	Requisites map[string]bool // подмножество Links: ресурсы, нужные для отображения страницы
 }
 
 // NewHTMLParser инициализирует HTMLParser
 func NewHTMLParser() LinkParser {
 	return &HTMLParser{
-		Links: make(map[string]bool),
+		Links:      make(map[string]bool),
+		Requisites: make(map[string]bool),
 	}
 }
 
@@ -49,9 +58,13 @@ func (p *HTMLParser) Parse(r io.Reader) error {
 	var walk func(*html.Node)
 	walk = func(n *html.Node) {
 		if n.Type == html.ElementNode {
+			requisite := isRequisite(n)
 			for _, attr := range n.Attr {
 				if attr.Key == "href" || attr.Key == "src" {
-					p.extractAndAddLink(attr.Val)
+					link := p.extractAndAddLink(attr.Val)
+					if requisite {
+						p.Requisites[link] = true
+					}
 				}
 			}
 		}
@@ -77,9 +90,40 @@ func (p *HTMLParser) GetLinks() []string {
 	return links
 }
 
-// extractAndAddLink добавляет ссылку к множеству ссылок
-func (p *HTMLParser) extractAndAddLink(link string) {
+// GetRequisites возвращает слайс ссылок на ресурсы, нужные для отображения страницы
+func (p *HTMLParser) GetRequisites() []string {
+	if len(p.Requisites) == 0 {
+		return nil
+	}
+
+	links := make([]string, 0, len(p.Requisites))
+	for link := range p.Requisites {
+		links = append(links, link)
+	}
+	return links
+}
+
+// extractAndAddLink добавляет ссылку к множеству ссылок и возвращает её в обработанном виде
+func (p *HTMLParser) extractAndAddLink(link string) string {
 	link = strings.TrimRight(link, "/")
 	link = strings.TrimSpace(link)
 	p.Links[link] = true
+	return link
+}
+
+// isRequisite является ли ссылка тега ресурсом страницы: src у SrcAtoms
+// или <link> со стилями и иконками
+func isRequisite(n *html.Node) bool {
+	if SrcAtoms[n.DataAtom] {
+		return true
+	}
+	if n.DataAtom != atom.Link {
+		return false
+	}
+	for _, attr := range n.Attr {
+		if attr.Key == "rel" && RequisiteRels[strings.ToLower(strings.TrimSpace(attr.Val))] {
+			return true
+		}
+	}
+	return false
 }
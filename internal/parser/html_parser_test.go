@@ -158,3 +158,40 @@ func TestHTMLParser_Parse(t *testing.T) {
 		})
 	}
 }
+
+// TestHTMLParser_GetRequisites тест выделения ресурсов страницы
+func TestHTMLParser_GetRequisites(t *testing.T) {
+	html := `
+		<html>
+			<head>
+				<link rel="stylesheet" href="/style.css">
+				<link rel="icon" href="/favicon.ico">
+				<link rel="canonical" href="/page">
+				<script src="/app.js"></script>
+			</head>
+			<body>
+				<a href="/about">About</a>
+				<img src="/logo.png">
+			</body>
+		</html>`
+
+	parser := NewHTMLParser()
+	if err := parser.Parse(strings.NewReader(html)); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]bool{"/style.css": true, "/favicon.ico": true, "/app.js": true, "/logo.png": true}
+	requisites := parser.GetRequisites()
+	if len(requisites) != len(expect) {
+		t.Errorf("Expected %d requisites, got %d: %+v", len(expect), len(requisites), requisites)
+	}
+	for _, link := range requisites {
+		if !expect[link] {
+			t.Errorf("Unexpected requisite %q", link)
+		}
+	}
+
+	if len(parser.GetLinks()) != 6 {
+		t.Errorf("Expected requisites to be included in links, got %+v", parser.GetLinks())
+	}
+}
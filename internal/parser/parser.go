@@ -6,4 +6,5 @@ import "io"
 type LinkParser interface {
 	Parse(r io.Reader) error
 	GetLinks() []string
+	GetRequisites() []string
 }
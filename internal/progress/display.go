@@ -0,0 +1,182 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RefreshInterval период перерисовки области состояния
+const RefreshInterval = 200 * time.Millisecond
+
+// defaultWidth ширина терминала, если её не удалось узнать из $COLUMNS
+const defaultWidth = 80
+
+// Display область состояния внизу терминала. Display также служит writer'ом журнала:
+// перед выводом сообщения область стирается и рисуется заново под ним
+type Display struct {
+	w       io.Writer
+	tracker *Tracker
+	width   int
+
+	mu        sync.Mutex
+	lines     int // сколько строк области сейчас на экране
+	lastBytes int64
+	lastTime  time.Time
+	rate      float64 // байт в секунду
+	stopped   bool
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDisplay инициализирует Display, выводящий состояние tracker в w
+func NewDisplay(w io.Writer, tracker *Tracker) *Display {
+	width := defaultWidth
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		width = columns
+	}
+	return &Display{
+		w:       w,
+		tracker: tracker,
+		width:   width,
+	}
+}
+
+// IsTerminal подключён ли файл к терминалу
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Start запускает периодическую перерисовку области состояния
+func (d *Display) Start() {
+	d.mu.Lock()
+	d.lastTime = time.Now()
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	d.mu.Unlock()
+
+	d.tracker.OnClose(d.Stop)
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(RefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				d.refresh()
+			}
+		}
+	}()
+}
+
+// Stop останавливает перерисовку и стирает область состояния; повторные вызовы ничего не делают
+func (d *Display) Stop() {
+	d.stopOnce.Do(func() {
+		d.mu.Lock()
+		stop, done := d.stop, d.done
+		d.mu.Unlock()
+		if stop == nil {
+			return
+		}
+		close(stop)
+		<-done
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.clear()
+		d.stopped = true
+	})
+}
+
+// Write выводит сообщение журнала над областью состояния
+func (d *Display) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.clear()
+	n, err := d.w.Write(p)
+	d.draw()
+	return n, err
+}
+
+// refresh пересчитывает скорость и перерисовывает область
+func (d *Display) refresh() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snap := d.tracker.Snapshot()
+	now := time.Now()
+	if elapsed := now.Sub(d.lastTime).Seconds(); elapsed > 0 {
+		// сглаживание, чтобы скорость не прыгала между перерисовками
+		current := float64(snap.Bytes-d.lastBytes) / elapsed
+		d.rate = 0.7*d.rate + 0.3*current
+	}
+	d.lastBytes, d.lastTime = snap.Bytes, now
+
+	d.clear()
+	d.draw()
+}
+
+// clear стирает область состояния; курсор остаётся в начале её первой строки
+func (d *Display) clear() {
+	for ; d.lines > 0; d.lines-- {
+		fmt.Fprint(d.w, "\x1b[1A\x1b[2K")
+	}
+	fmt.Fprint(d.w, "\r")
+}
+
+// draw рисует область состояния под курсором
+func (d *Display) draw() {
+	if d.stop == nil || d.stopped {
+		return
+	}
+	for _, line := range Render(d.tracker.Snapshot(), d.rate, d.width) {
+		fmt.Fprintln(d.w, line)
+		d.lines++
+	}
+}
+
+// Render строки области состояния: сводка и URL, которые скачивают воркеры
+func Render(snap Snapshot, rate float64, width int) []string {
+	lines := []string{truncate(fmt.Sprintf(
+		"queued %d  active %d  done %d  failed %d  skipped %d  %s  %s/s",
+		snap.Queued, len(snap.Active), snap.Done, snap.Failed, snap.Skipped,
+		FormatBytes(snap.Bytes), FormatBytes(int64(rate)),
+	), width)}
+
+	for _, fetch := range snap.Active {
+		lines = append(lines, truncate(fmt.Sprintf("  [%d] %s", fetch.Worker, fetch.URL), width))
+	}
+	return lines
+}
+
+// FormatBytes размер в единицах, кратных 1024
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// truncate обрезает строку до ширины терминала, чтобы она не переносилась
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 1 || len(runes) < width {
+		return s
+	}
+	return string(runes[:width-2]) + "…"
+}
@@ -0,0 +1,113 @@
+package progress
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestTrackerSnapshot тест сбора состояния обхода
+func TestTrackerSnapshot(t *testing.T) {
+	tracker := NewTracker()
+	tracker.SetPending(func() int { return 5 })
+	tracker.Start(2, "https://example.com/b")
+	tracker.Start(0, "https://example.com/a")
+	tracker.Start(1, "https://example.com/c")
+	tracker.Finish(1)
+	tracker.Done()
+	tracker.Done()
+	tracker.Failed()
+	tracker.Skipped()
+	tracker.AddBytes(2048)
+
+	expect := Snapshot{
+		Queued:  5,
+		Done:    2,
+		Failed:  1,
+		Skipped: 1,
+		Bytes:   2048,
+		Active: []Fetch{
+			{Worker: 0, URL: "https://example.com/a"},
+			{Worker: 2, URL: "https://example.com/b"},
+		},
+	}
+	if snap := tracker.Snapshot(); !reflect.DeepEqual(snap, expect) {
+		t.Errorf("expected %+v, got %+v", expect, snap)
+	}
+
+	var nilTracker *Tracker
+	nilTracker.Start(0, "https://example.com/")
+	nilTracker.Done()
+	nilTracker.Close()
+	if snap := nilTracker.Snapshot(); !reflect.DeepEqual(snap, Snapshot{}) {
+		t.Errorf("expected empty snapshot for nil tracker, got %+v", snap)
+	}
+}
+
+// TestRender тест строк области состояния
+func TestRender(t *testing.T) {
+	snap := Snapshot{
+		Queued: 3,
+		Done:   10,
+		Bytes:  3 << 20,
+		Active: []Fetch{{Worker: 1, URL: "https://example.com/" + strings.Repeat("x", 100)}},
+	}
+
+	lines := Render(snap, 1536, 40)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "queued 3  active 1  done 10") {
+		t.Errorf("unexpected summary %q", lines[0])
+	}
+	if len([]rune(lines[1])) >= 40 || !strings.HasPrefix(lines[1], "  [1] https://example.com/") {
+		t.Errorf("expected truncated worker line, got %q", lines[1])
+	}
+
+	if line := Render(snap, 1536, 200)[0]; !strings.Contains(line, "3.0 MiB  1.5 KiB/s") {
+		t.Errorf("expected bytes and rate in %q", line)
+	}
+}
+
+// TestDisplayWrite тест вывода журнала над областью состояния
+func TestDisplayWrite(t *testing.T) {
+	var buf bytes.Buffer
+	tracker := NewTracker()
+	tracker.Start(0, "https://example.com/")
+	d := NewDisplay(&buf, tracker)
+	d.Start()
+
+	d.Write([]byte("first\n"))
+	d.Write([]byte("second\n"))
+	tracker.Close()
+
+	out := buf.String()
+	// перед вторым сообщением нарисованная область (2 строки) стирается
+	if !strings.Contains(out, "first\n") || !strings.Contains(out, "\x1b[1A\x1b[2K\x1b[1A\x1b[2K\rsecond\n") {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	buf.Reset()
+	d.Write([]byte("after\n"))
+	if buf.String() != "\rafter\n" {
+		t.Errorf("expected no status area after close, got %q", buf.String())
+	}
+}
+
+// TestFormatBytes тест форматирования размеров
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:         "0 B",
+		1023:      "1023 B",
+		1024:      "1.0 KiB",
+		1536:      "1.5 KiB",
+		5 << 30:   "5.0 GiB",
+		123456789: "117.7 MiB",
+	}
+	for n, expect := range tests {
+		if got := FormatBytes(n); got != expect {
+			t.Errorf("FormatBytes(%d): expected %q, got %q", n, expect, got)
+		}
+	}
+}
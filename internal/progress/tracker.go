@@ -0,0 +1,163 @@
+package progress
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Tracker собирает состояние обхода для отображения прогресса.
+// Методы безопасны для nil получателя - прогресс тогда не отслеживается
+type Tracker struct {
+	done    atomic.Int64
+	failed  atomic.Int64
+	skipped atomic.Int64
+	bytes   atomic.Int64
+
+	mu      sync.Mutex
+	active  map[int]string // URL, которые сейчас скачивает каждый воркер
+	pending func() int
+	onClose []func()
+}
+
+// NewTracker инициализирует Tracker
+func NewTracker() *Tracker {
+	return &Tracker{active: make(map[int]string)}
+}
+
+// Snapshot состояние обхода в момент времени
+type Snapshot struct {
+	Queued  int
+	Done    int64
+	Failed  int64
+	Skipped int64
+	Bytes   int64
+	Active  []Fetch // по возрастанию номера воркера
+}
+
+// Fetch URL, который скачивает воркер
+type Fetch struct {
+	Worker int
+	URL    string
+}
+
+// SetPending задаёт функцию, возвращающую число задач, ожидающих воркера
+func (t *Tracker) SetPending(pending func() int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = pending
+}
+
+// Start отмечает, что воркер worker начал скачивать url
+func (t *Tracker) Start(worker int, url string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active[worker] = url
+}
+
+// Finish отмечает, что воркер worker освободился
+func (t *Tracker) Finish(worker int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.active, worker)
+}
+
+// Active число воркеров, которые сейчас скачивают документы
+func (t *Tracker) Active() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// Done отмечает скачанный документ
+func (t *Tracker) Done() {
+	if t != nil {
+		t.done.Add(1)
+	}
+}
+
+// Failed отмечает документ, который не удалось скачать или сохранить
+func (t *Tracker) Failed() {
+	if t != nil {
+		t.failed.Add(1)
+	}
+}
+
+// Skipped отмечает отброшенную ссылку
+func (t *Tracker) Skipped() {
+	if t != nil {
+		t.skipped.Add(1)
+	}
+}
+
+// AddBytes учитывает скачанные байты
+func (t *Tracker) AddBytes(n int64) {
+	if t != nil {
+		t.bytes.Add(n)
+	}
+}
+
+// OnClose регистрирует функцию, вызываемую при завершении обхода
+func (t *Tracker) OnClose(fn func()) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onClose = append(t.onClose, fn)
+}
+
+// Close сообщает о завершении обхода: отображение прогресса должно убраться
+// с экрана до того, как Engine выведет результаты
+func (t *Tracker) Close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	hooks := t.onClose
+	t.onClose = nil
+	t.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// Snapshot возвращает текущее состояние обхода
+func (t *Tracker) Snapshot() Snapshot {
+	if t == nil {
+		return Snapshot{}
+	}
+
+	snap := Snapshot{
+		Done:    t.done.Load(),
+		Failed:  t.failed.Load(),
+		Skipped: t.skipped.Load(),
+		Bytes:   t.bytes.Load(),
+	}
+
+	t.mu.Lock()
+	pending := t.pending
+	for worker, url := range t.active {
+		snap.Active = append(snap.Active, Fetch{Worker: worker, URL: url})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(snap.Active, func(i, j int) bool { return snap.Active[i].Worker < snap.Active[j].Worker })
+	if pending != nil {
+		snap.Queued = max(0, pending())
+	}
+	return snap
+}
@@ -3,18 +3,24 @@ package queue
 import (
 	"mirror-wget/internal/normalizer"
 	"sync"
+	"time"
 )
 
 // Item содержит ссылку и глубину рекурсии, на которой он был получен
 type Item struct {
-	URL   *normalizer.NormalizedURL
-	Depth int
+	URL       *normalizer.NormalizedURL
+	Depth     int
+	Requisite bool      // ссылка на ресурс, нужный для отображения страницы (-p)
+	Referer   string    // страница, на которой найдена ссылка; для стартовых URL - --referer
+	Attempt   int       // номер повторной попытки загрузки, 0 - первая попытка
+	NotBefore time.Time // повтор не выдаётся воркерам раньше этого времени
 }
 
 // Queue интерфейс очереди
 type Queue interface {
 	Push(item Item) bool
 	Pop() (Item, bool)
+	Len() int
 }
 
 // sliceQueue реализация интерфейса очереди
@@ -49,3 +55,10 @@ func (q *sliceQueue) Pop() (Item, bool) {
 	q.queue = q.queue[1:]
 	return item, true
 }
+
+// Len возвращает количество элементов в очереди
+func (q *sliceQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
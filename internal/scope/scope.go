@@ -0,0 +1,136 @@
+package scope
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Scope определяет, какие URL входят в область обхода и какие файлы сохраняются.
+// Стартовые URL могут добавляться во время обхода, когда стартовый URL перенаправляет на другой адрес
+type Scope struct {
+	mu       sync.RWMutex
+	hosts    map[string]bool     // хосты стартовых URL
+	parents  map[string][]string // каталоги стартовых URL по хостам для -np
+	domains  []string            // -D
+	noParent bool                // -np
+	accept   []string            // -A
+	reject   []string            // -R
+}
+
+// NewScope инициализирует Scope
+func NewScope(domains []string, noParent bool, accept, reject []string) *Scope {
+	normDomains := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.Trim(domain, ". "))
+		if domain != "" {
+			normDomains = append(normDomains, domain)
+		}
+	}
+
+	return &Scope{
+		hosts:    make(map[string]bool),
+		parents:  make(map[string][]string),
+		domains:  normDomains,
+		noParent: noParent,
+		accept:   accept,
+		reject:   reject,
+	}
+}
+
+// AddSeed добавляет стартовый URL: его хост и каталог входят в область обхода
+func (s *Scope) AddSeed(u *url.URL) {
+	host := strings.ToLower(u.Host)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hosts[host] = true
+	s.parents[host] = append(s.parents[host], parentDir(u.Path))
+}
+
+// Allowed проверяет, входит ли URL в область обхода по хосту и каталогу
+func (s *Scope) Allowed(u *url.URL) bool {
+	host := strings.ToLower(u.Host)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.hosts[host] {
+		return !s.noParent || s.underParent(host, u.Path)
+	}
+	return s.matchDomain(u.Hostname())
+}
+
+// Accepted проверяет имя файла по спискам -A и -R
+func (s *Scope) Accepted(u *url.URL) bool {
+	name := fileName(u.Path)
+
+	if len(s.accept) > 0 && !matchAny(s.accept, name) {
+		return false
+	}
+	return !matchAny(s.reject, name)
+}
+
+// underParent находится ли путь внутри одного из каталогов стартовых URL хоста
+func (s *Scope) underParent(host, p string) bool {
+	if p == "" {
+		p = "/"
+	}
+	for _, parent := range s.parents[host] {
+		if strings.HasPrefix(p, parent) || p+"/" == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain совпадает ли имя хоста с одним из доменов -D или его поддоменом
+func (s *Scope) matchDomain(hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	for _, domain := range s.domains {
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// parentDir возвращает каталог URL с завершающим слешем.
+// Путь без расширения, как и в normalizer, считается каталогом
+func parentDir(p string) string {
+	switch {
+	case p == "":
+		return "/"
+	case strings.HasSuffix(p, "/"):
+		return p
+	case filepath.Ext(p) == "":
+		return p + "/"
+	default:
+		dir, _ := path.Split(p)
+		return dir
+	}
+}
+
+// fileName возвращает имя сохраняемого файла; каталог сохраняется как index.html
+func fileName(p string) string {
+	if p == "" || strings.HasSuffix(p, "/") || filepath.Ext(p) == "" {
+		return "index.html"
+	}
+	return path.Base(p)
+}
+
+// matchAny проверяет имя по списку: элементы с символами *?[ считаются шаблонами,
+// остальные - суффиксами, как в wget
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, err := path.Match(pattern, name); err == nil && ok {
+				return true
+			}
+			continue
+		}
+		if strings.HasSuffix(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
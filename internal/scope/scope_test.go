@@ -0,0 +1,137 @@
+package scope
+
+import (
+	"net/url"
+	"testing"
+)
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// TestScopeAllowed тест области обхода по хостам, доменам и -np
+func TestScopeAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		seed     string
+		domains  []string
+		noParent bool
+		url      string
+		expect   bool
+	}{
+		{
+			name:   "same host",
+			seed:   "https://example.com/docs/",
+			url:    "https://example.com/blog/post.html",
+			expect: true,
+		},
+		{
+			name:   "other host",
+			seed:   "https://example.com/",
+			url:    "https://other.com/",
+			expect: false,
+		},
+		{
+			name:    "domain list",
+			seed:    "https://example.com/",
+			domains: []string{"cdn.net"},
+			url:     "https://static.cdn.net/app.js",
+			expect:  true,
+		},
+		{
+			name:    "domain list does not match suffix of other name",
+			seed:    "https://example.com/",
+			domains: []string{"cdn.net"},
+			url:     "https://evilcdn.net/app.js",
+			expect:  false,
+		},
+		{
+			name:     "no parent inside",
+			seed:     "https://example.com/docs/index.html",
+			noParent: true,
+			url:      "https://example.com/docs/v2/page.html",
+			expect:   true,
+		},
+		{
+			name:     "no parent outside",
+			seed:     "https://example.com/docs/index.html",
+			noParent: true,
+			url:      "https://example.com/blog/",
+			expect:   false,
+		},
+		{
+			name:     "no parent directory without slash",
+			seed:     "https://example.com/docs",
+			noParent: true,
+			url:      "https://example.com/docs/",
+			expect:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScope(tt.domains, tt.noParent, nil, nil)
+			s.AddSeed(mustParse(t, tt.seed))
+
+			if got := s.Allowed(mustParse(t, tt.url)); got != tt.expect {
+				t.Errorf("Allowed(%q) = %v, expected %v", tt.url, got, tt.expect)
+			}
+		})
+	}
+}
+
+// TestScopeAccepted тест списков -A и -R
+func TestScopeAccepted(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept []string
+		reject []string
+		url    string
+		expect bool
+	}{
+		{
+			name:   "no lists",
+			url:    "https://example.com/a.zip",
+			expect: true,
+		},
+		{
+			name:   "accept suffix",
+			accept: []string{"jpg", "png"},
+			url:    "https://example.com/img/a.png",
+			expect: true,
+		},
+		{
+			name:   "accept suffix mismatch",
+			accept: []string{"jpg", "png"},
+			url:    "https://example.com/a.zip",
+			expect: false,
+		},
+		{
+			name:   "reject pattern",
+			reject: []string{"*.mp4"},
+			url:    "https://example.com/video/a.mp4",
+			expect: false,
+		},
+		{
+			name:   "directory is saved as index.html",
+			accept: []string{"html"},
+			url:    "https://example.com/docs",
+			expect: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScope(nil, false, tt.accept, tt.reject)
+
+			if got := s.Accepted(mustParse(t, tt.url)); got != tt.expect {
+				t.Errorf("Accepted(%q) = %v, expected %v", tt.url, got, tt.expect)
+			}
+		})
+	}
+}
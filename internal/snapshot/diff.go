@@ -0,0 +1,95 @@
+package snapshot
+
+import (
+	"io/fs"
+	"mirror-wget/internal/journal"
+	"mirror-wget/internal/storage"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChangeKind вид изменения файла между снимками
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "A"
+	Removed  ChangeKind = "D"
+	Modified ChangeKind = "M"
+)
+
+// Change изменение файла между снимками
+type Change struct {
+	Kind ChangeKind
+	Path string // путь относительно корня снимка
+}
+
+// Diff сравнивает два снимка зеркала по содержимому файлов
+func Diff(oldRoot, newRoot string) ([]Change, error) {
+	oldFiles, err := hashTree(oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := hashTree(newRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for path, oldHash := range oldFiles {
+		newHash, ok := newFiles[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: Removed, Path: path})
+		case newHash != oldHash:
+			changes = append(changes, Change{Kind: Modified, Path: path})
+		}
+	}
+	for path := range newFiles {
+		if _, ok := oldFiles[path]; !ok {
+			changes = append(changes, Change{Kind: Added, Path: path})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// hashTree возвращает sha256 всех файлов снимка, кроме каталога состояния задания
+func hashTree(root string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := walkFiles(root, func(path, rel string) error {
+		_, sum, err := journal.HashFile(path)
+		if err != nil {
+			return err
+		}
+		files[rel] = sum
+		return nil
+	})
+	return files, err
+}
+
+// walkFiles обходит обычные файлы снимка, пропуская каталог состояния задания
+// и временные файлы прерванных загрузок
+func walkFiles(root string, fn func(path, rel string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == journal.DirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(path, storage.PartSuffix) || strings.HasSuffix(path, storage.MetaSuffix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(rel))
+	})
+}
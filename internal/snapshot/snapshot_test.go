@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"mirror-wget/internal/journal"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func _writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// TestDiff тест сравнения снимков
+func TestDiff(t *testing.T) {
+	oldRoot := _writeTree(t, map[string]string{
+		"index.html":              "v1",
+		"style.css":               "body{}",
+		"old.html":                "gone",
+		journal.DirName + "/x.js": "state",
+	})
+	newRoot := _writeTree(t, map[string]string{
+		"index.html": "v2",
+		"style.css":  "body{}",
+		"docs/a.pdf": "new",
+	})
+
+	changes, err := Diff(oldRoot, newRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []Change{
+		{Kind: Added, Path: "docs/a.pdf"},
+		{Kind: Modified, Path: "index.html"},
+		{Kind: Removed, Path: "old.html"},
+	}
+	if !reflect.DeepEqual(changes, expect) {
+		t.Errorf("expected %v, got %v", expect, changes)
+	}
+}
+
+// TestVerifyLinks тест поиска битых относительных ссылок
+func TestVerifyLinks(t *testing.T) {
+	root := _writeTree(t, map[string]string{
+		"index.html":      `<a href="docs/">docs</a><a href="missing.html">x</a><a href="https://example.com/">ext</a><img src="img/logo.png">`,
+		"docs/index.html": `<link rel="stylesheet" href="../style.css"><a href="#top">top</a>`,
+		"style.css":       `body { background: url("img/bg.png"); }`,
+		"img/logo.png":    "png",
+	})
+
+	problems, err := VerifyLinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []Problem{
+		{Path: filepath.Join(root, "index.html"), Detail: "broken link missing.html"},
+		{Path: filepath.Join(root, "style.css"), Detail: "broken link img/bg.png"},
+	}
+	if !reflect.DeepEqual(problems, expect) {
+		t.Errorf("expected %v, got %v", expect, problems)
+	}
+}
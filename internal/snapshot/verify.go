@@ -0,0 +1,123 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"mirror-wget/internal/journal"
+	"mirror-wget/internal/parser"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problem проблема, найденная при проверке зеркала
+type Problem struct {
+	Path   string // файл зеркала, в котором найдена проблема
+	Detail string
+}
+
+// String описание проблемы
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Detail)
+}
+
+// VerifyFiles сверяет сохранённые файлы с размерами и хешами из журнала задания
+func VerifyFiles(state *journal.State) []Problem {
+	var problems []Problem
+	for _, entry := range state.Done {
+		if entry.Path == "" || entry.SHA256 == "" {
+			continue
+		}
+
+		size, sum, err := journal.HashFile(entry.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, Problem{Path: entry.Path, Detail: "missing file for " + entry.URL})
+		case err != nil:
+			problems = append(problems, Problem{Path: entry.Path, Detail: err.Error()})
+		case size != entry.Size || sum != entry.SHA256:
+			problems = append(problems, Problem{Path: entry.Path, Detail: "content differs from journal"})
+		}
+	}
+
+	sortProblems(problems)
+	return problems
+}
+
+// VerifyLinks проверяет, что относительные ссылки в HTML и CSS файлах зеркала ведут на существующие файлы
+func VerifyLinks(root string) ([]Problem, error) {
+	var problems []Problem
+	err := walkFiles(root, func(path, _ string) error {
+		var p parser.LinkParser
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".html", ".htm":
+			p = parser.NewHTMLParser()
+		case ".css":
+			p = parser.NewCSSParser()
+		default:
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := p.Parse(f); err != nil {
+			problems = append(problems, Problem{Path: path, Detail: "parse failed: " + err.Error()})
+			return nil
+		}
+
+		for _, link := range p.GetLinks() {
+			target, ok := localTarget(path, link)
+			if !ok {
+				continue
+			}
+			if !exists(target) {
+				problems = append(problems, Problem{Path: path, Detail: "broken link " + link})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortProblems(problems)
+	return problems, nil
+}
+
+// localTarget возвращает путь к файлу, на который указывает относительная ссылка из файла path.
+// Абсолютные URL, ссылки от корня сайта и якоря не проверяются
+func localTarget(path, link string) (string, bool) {
+	if link == "" || strings.HasPrefix(link, "#") || strings.HasPrefix(link, "/") {
+		return "", false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+
+	return filepath.Join(filepath.Dir(path), filepath.FromSlash(u.Path)), true
+}
+
+// exists существует ли файл; для каталога проверяется его index.html
+func exists(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		return exists(filepath.Join(path, "index.html"))
+	}
+	return true
+}
+
+// sortProblems упорядочивает проблемы по файлу
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+}
@@ -37,7 +37,7 @@ func initCSSDownloadMap() *sync.Map {
 }
 
 func runCSSRewriteTest(t *testing.T, input, expect string) {
-	docURL, err := normalizer.NewNormalizedUrl("http://localhost:8080/assets/css/style.css")
+	docURL, err := normalizer.NewNormalizedURL("http://localhost:8080/assets/css/style.css")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -70,7 +70,7 @@ func initDownLoadMap() *sync.Map {
 }
 
 func runHTMLRewriteTest(t *testing.T, currentDocURL string, data, expect string) {
-	docURL, err := normalizer.NewNormalizedUrl(currentDocURL)
+	docURL, err := normalizer.NewNormalizedURL(currentDocURL)
 	if err != nil {
 		t.Fatal(err)
 	}
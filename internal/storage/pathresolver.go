@@ -64,11 +64,11 @@ func (pr *PathResolver) Resolve(link string) (string, bool) {
 
 // makeRelativePath создает относительный путь от текущего документа к целевому
 func (pr *PathResolver) makeRelativePath(target *normalizer.NormalizedURL) (string, error) {
-	currentPath, err := pr.currentDocURL.SavePath()
+	currentPath, err := pr.savePath(pr.currentDocURL)
 	if err != nil {
 		return "", err
 	}
-	targetPath, err := target.SavePath()
+	targetPath, err := pr.savePath(target)
 	if err != nil {
 		return "", err
 	}
@@ -97,6 +97,17 @@ func (pr *PathResolver) makeRelativePath(target *normalizer.NormalizedURL) (stri
 	return relPath, nil
 }
 
+// savePath возвращает путь, по которому документ сохранён на диске.
+// Карта скачанных файлов учитывает раскладку (-P, -nH, --cut-dirs), поэтому путь берётся из неё
+func (pr *PathResolver) savePath(u *normalizer.NormalizedURL) (string, error) {
+	if fp, ok := pr.downloadedMap.Load(u.String()); ok {
+		if p, ok := fp.(string); ok {
+			return p, nil
+		}
+	}
+	return u.SavePath()
+}
+
 func (pr *PathResolver) isAbsoluteURL(url string) bool {
-	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "//")
 }
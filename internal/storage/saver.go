@@ -1,28 +1,140 @@
 package storage
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 )
 
-// Save сохраняет файл, если необходимо создает директории по пути к файлу
-func Save(path string, data []byte) (int, error) {
-	dir := filepath.Dir(path)
-	err := os.MkdirAll(dir, os.ModePerm)
+// PartSuffix суффикс временного файла, в который пишется скачиваемый документ
+const PartSuffix = ".part"
+
+// MetaSuffix суффикс файла с PartInfo рядом с временным файлом
+const MetaSuffix = ".part.meta"
+
+// PartInfo сведения о документе, нужные, чтобы продолжить прерванную загрузку
+type PartInfo struct {
+	Validator   string `json:"validator"` // ETag или Last-Modified документа
+	ContentType string `json:"content_type"`
+}
+
+// File документ, который пишется во временный файл рядом с итоговым и появляется
+// под своим именем только после Commit, чтобы прерванная загрузка не оставляла обрезанных файлов
+type File struct {
+	path   string
+	file   *os.File
+	offset int64 // сколько байт было во временном файле при открытии
+}
+
+// Create создаёт временный файл для документа path; если необходимо, создаёт директории
+func Create(path string) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path + PartSuffix)
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(path + MetaSuffix)
+	return &File{path: path, file: file}, nil
+}
+
+// OpenPart открывает временный файл прерванной загрузки документа path для дозаписи.
+// Если временного файла нет, возвращает ошибку os.ErrNotExist
+func OpenPart(path string) (*File, PartInfo, error) {
+	var info PartInfo
+	data, err := os.ReadFile(path + MetaSuffix)
 	if err != nil {
-		return 0, err
+		return nil, info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, info, err
 	}
 
-	file, err := os.Create(path)
+	file, err := os.OpenFile(path+PartSuffix, os.O_RDWR, 0)
 	if err != nil {
-		return 0, err
+		return nil, info, err
+	}
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		file.Close()
+		return nil, info, err
+	}
+	return &File{path: path, file: file, offset: offset}, info, nil
+}
+
+// Write реализует io.Writer
+func (f *File) Write(p []byte) (int, error) {
+	return f.file.Write(p)
+}
+
+// Path итоговый путь документа
+func (f *File) Path() string {
+	return f.path
+}
+
+// Offset сколько байт документа уже было записано до открытия
+func (f *File) Offset() int64 {
+	return f.offset
+}
+
+// Existing читает байты, записанные до открытия
+func (f *File) Existing() io.Reader {
+	return io.NewSectionReader(f.file, 0, f.offset)
+}
+
+// Truncate отбрасывает записанные данные, чтобы записать документ заново
+func (f *File) Truncate() error {
+	if err := f.file.Truncate(0); err != nil {
+		return err
 	}
-	defer file.Close()
+	f.offset = 0
+	_, err := f.file.Seek(0, io.SeekStart)
+	return err
+}
 
-	n, err := file.Write(data)
+// SetInfo сохраняет сведения, по которым загрузку можно будет продолжить через OpenPart
+func (f *File) SetInfo(info PartInfo) error {
+	data, err := json.Marshal(info)
 	if err != nil {
-		return n, err
+		return err
 	}
+	return os.WriteFile(f.path+MetaSuffix, data, 0o644)
+}
+
+// Commit закрывает временный файл и переименовывает его в итоговый
+func (f *File) Commit() error {
+	return f.CommitAs(f.path)
+}
+
+// CommitAs закрывает временный файл и сохраняет документ под путём path,
+// например по адресу, на который вёл редирект. Path затем возвращает path
+func (f *File) CommitAs(path string) error {
+	os.Remove(f.path + MetaSuffix)
+	if err := f.file.Close(); err != nil {
+		os.Remove(f.file.Name())
+		return err
+	}
+	if path != f.path {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			os.Remove(f.file.Name())
+			return err
+		}
+		f.path = path
+	}
+	return os.Rename(f.file.Name(), path)
+}
+
+// Close закрывает временный файл, оставляя его для продолжения загрузки
+func (f *File) Close() error {
+	return f.file.Close()
+}
 
-	return n, nil
+// Abort закрывает и удаляет временный файл
+func (f *File) Abort() error {
+	os.Remove(f.path + MetaSuffix)
+	return errors.Join(f.file.Close(), os.Remove(f.file.Name()))
 }
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFileCommit тест появления документа только после Commit
+func TestFileCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "example.com", "docs", "index.html")
+
+	f, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("<html></html>")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected no file before commit, got %v", err)
+	}
+
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "<html></html>" {
+		t.Errorf("unexpected file %q, %v", data, err)
+	}
+	if _, err := os.Stat(path + PartSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected temp file to be renamed, got %v", err)
+	}
+}
+
+// TestFileAbort тест удаления временного файла прерванной загрузки
+func TestFileAbort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.zip")
+
+	f, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("partial"))
+	if err := f.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{path, path + PartSuffix} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be absent, got %v", p, err)
+		}
+	}
+}